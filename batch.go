@@ -0,0 +1,168 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// Loader loads a graph file, returning the adjacency matrix, the node names if the format has them
+// and whether the graph is directed. Loaders that can count the nodes before allocating the
+// matrix refuse graphs with more than maxSize nodes.
+type Loader func(name string, directed bool, maxSize int) (*mat.Dense, []string, bool, error)
+
+// Loaders are the graph file loaders by file extension
+var Loaders = map[string]Loader{
+	".csv": func(name string, directed bool, maxSize int) (*mat.Dense, []string, bool, error) {
+		adjacency, err := LoadCSV(name)
+		return adjacency, nil, directed, err
+	},
+	".el": func(name string, directed bool, maxSize int) (*mat.Dense, []string, bool, error) {
+		adjacency, err := LoadEdgeList(name, directed, maxSize)
+		return adjacency, nil, directed, err
+	},
+	".graphml": func(name string, directed bool, maxSize int) (*mat.Dense, []string, bool, error) {
+		return LoadGraphML(name)
+	},
+	".dot": func(name string, directed bool, maxSize int) (*mat.Dense, []string, bool, error) {
+		return LoadDOT(name)
+	},
+}
+
+// BatchOptions are the options for analyzing a directory of graphs, the pipeline options are
+// the same as for a single graph, but Directed can be overridden by the graph files
+type BatchOptions struct {
+	PipelineOptions
+	Workers   int
+	OutputDir string
+	// MaxSize is the largest number of nodes analyzed, 0 disables the limit
+	MaxSize int
+}
+
+// Batch analyzes every graph file in the directory with a pool of workers, the results for
+// each graph are written to a file named after it with the extension .out in the output
+// directory, which defaults to the working directory. It returns the
+// number of graphs and an error for each graph that failed. Once ctx is done the remaining graphs
+// fail with an error wrapping spectral.ErrCanceled.
+func Batch(ctx context.Context, directory string, options BatchOptions) (int, []error) {
+	entries, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return 0, []error{err}
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := Loaders[strings.ToLower(filepath.Ext(entry.Name()))]; ok {
+			files = append(files, filepath.Join(directory, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	workers := options.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	jobs, failures := make(chan int), make([]error, len(files))
+	var wait sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					failures[job] = fmt.Errorf("%s: %w: %v", files[job], spectral.ErrCanceled, ctx.Err())
+					continue
+				}
+				failures[job] = AnalyzeFile(files[job], options)
+			}
+		}()
+	}
+	for job := range files {
+		jobs <- job
+	}
+	close(jobs)
+	wait.Wait()
+
+	var errs []error
+	for _, failure := range failures {
+		if failure != nil {
+			errs = append(errs, failure)
+		}
+	}
+	return len(files), errs
+}
+
+// AnalyzeFile loads a graph file and writes its analysis to a file named after it
+func AnalyzeFile(name string, options BatchOptions) error {
+	load := Loaders[strings.ToLower(filepath.Ext(name))]
+	adjacency, _, directed, err := load(name, options.Directed, options.MaxSize)
+	if err != nil {
+		return err
+	}
+	size, _ := adjacency.Dims()
+	if err := CheckSize(size, options.MaxSize); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	file, err := os.Create(filepath.Join(options.OutputDir, filepath.Base(name)+".out"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	output := bufio.NewWriter(file)
+	err = Analyze(output, adjacency, directed, options)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return output.Flush()
+}
+
+// Analyze writes the analysis of the graph to output with the pipeline of a single graph: the
+// connected components, the eigenvalues and the rankings
+func Analyze(output io.Writer, adjacency *mat.Dense, directed bool, options BatchOptions) error {
+	pipeline := &Pipeline{
+		PipelineOptions: options.PipelineOptions,
+		Output:          output,
+		Warnings:        output,
+		Debug:           output,
+	}
+	pipeline.Directed = directed
+	components, err := pipeline.Clean(adjacency)
+	if err != nil {
+		return err
+	}
+	adjacency, err = pipeline.Prepare(adjacency, components)
+	if err != nil {
+		return err
+	}
+
+	graph := pipeline.Graph(adjacency)
+	spectrum, err := graph.Spectrum()
+	if err != nil {
+		return err
+	}
+	pipeline.Summarize(spectrum)
+	if err := spectral.Degenerate(adjacency); err != nil {
+		fmt.Fprintf(output, "warning: %v, the ranking is skipped\n", err)
+		return nil
+	}
+	_, err = pipeline.Rank(graph, spectrum)
+	return err
+}
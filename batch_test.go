@@ -0,0 +1,39 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+func TestBatchCanceled(t *testing.T) {
+	input, output := t.TempDir(), t.TempDir()
+	for _, name := range []string{"a.el", "b.el", "c.el"} {
+		if err := ioutil.WriteFile(filepath.Join(input, name), []byte("0 1\n1 2\n0 2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	count, errs := Batch(ctx, input, BatchOptions{Workers: 2, OutputDir: output})
+	if count != 3 || len(errs) != 3 {
+		t.Fatalf("%d graphs with %d errors, expected 3 graphs that fail", count, len(errs))
+	}
+	for _, err := range errs {
+		if !errors.Is(err, spectral.ErrCanceled) {
+			t.Errorf("error %v, expected %v", err, spectral.ErrCanceled)
+		}
+	}
+	// the canceled graphs aren't analyzed, so there are no outputs
+	if outputs, err := ioutil.ReadDir(output); err != nil || len(outputs) != 0 {
+		t.Errorf("%d outputs with the error %v after the context was done", len(outputs), err)
+	}
+}
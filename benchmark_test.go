@@ -0,0 +1,142 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// BenchmarkSizes are the graph sizes the benchmarks are run at
+var BenchmarkSizes = []int{5, 50, 200}
+
+// BenchmarkSeed seeds the random graphs and the neural weights of the benchmarks
+const BenchmarkSeed = 1
+
+// RandomGraph generates a random undirected graph with edge probability p
+func RandomGraph(rng *rand.Rand, size int, p float64) *mat.Dense {
+	adjacency := mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		for j := i + 1; j < size; j++ {
+			if rng.Float64() < p {
+				adjacency.Set(i, j, 1)
+				adjacency.Set(j, i, 1)
+			}
+		}
+	}
+	return adjacency
+}
+
+// randomGraphs runs bench as a sub-benchmark on a random undirected graph of each of the
+// benchmark sizes
+func randomGraphs(b *testing.B, bench func(b *testing.B, adjacency *mat.Dense)) {
+	for _, size := range BenchmarkSizes {
+		size := size
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			bench(b, RandomGraph(rand.New(rand.NewSource(BenchmarkSeed)), size, .2))
+		})
+	}
+}
+
+// SparseBenchmarkSize is the size of the sparse graph benchmark
+const SparseBenchmarkSize = 10000
+
+// RandomSparseGraph generates a random undirected sparse graph with the given average degree
+func RandomSparseGraph(rng *rand.Rand, size, degree int) *spectral.CSR {
+	edges := make([]spectral.Edge, 0, size*degree/2)
+	for i := 0; i < size*degree/2; i++ {
+		edges = append(edges, spectral.Edge{Source: rng.Intn(size), Destination: rng.Intn(size), Weight: 1})
+	}
+	return spectral.NewCSR(size, edges, false)
+}
+
+// BenchmarkPowerSparse benchmarks power iteration on a sparse matrix, the matrix-bytes metric
+// is the size of the sparse matrix, a dense matrix would be 8 bytes per entry
+func BenchmarkPowerSparse(b *testing.B) {
+	m := RandomSparseGraph(rand.New(rand.NewSource(BenchmarkSeed)), SparseBenchmarkSize, 10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		spectral.PowerIterationCSR(context.Background(), m, 1000, 1e-9)
+	}
+	b.ReportMetric(float64(m.Bytes()), "matrix-bytes")
+}
+
+// BenchmarkEigen benchmarks the eigendecomposition
+func BenchmarkEigen(b *testing.B) {
+	randomGraphs(b, func(b *testing.B, adjacency *mat.Dense) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			_, err := spectral.Decompose(adjacency, spectral.DecomposeOptions{})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkPCA benchmarks the principal component projection of the eigenvectors
+func BenchmarkPCA(b *testing.B) {
+	randomGraphs(b, func(b *testing.B, adjacency *mat.Dense) {
+		graph := spectral.NewGraph(adjacency)
+		_, _, err := graph.Eigen()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			_, err := graph.Project(2)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// benchmarkNeural benchmarks 16 epochs of neural mode, the training output is discarded
+func benchmarkNeural(b *testing.B, neural func(context.Context, *rand.Rand, int, *mat.CDense, []complex128, NeuralOptions) error) {
+	randomGraphs(b, func(b *testing.B, adjacency *mat.Dense) {
+		size, _ := adjacency.Dims()
+		vectors, values, err := spectral.NewGraph(adjacency).Eigen()
+		if err != nil {
+			b.Fatal(err)
+		}
+		Silence(b)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			err := neural(context.Background(), rand.New(rand.NewSource(BenchmarkSeed)), size, vectors, values, NeuralOptions{
+				Eta:        .3,
+				Iterations: 16,
+				Optimizer:  "sgd",
+				Layers:     1,
+				Clip:       1,
+				Loss:       "quadratic",
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkNeural benchmarks neural mode with complex weights
+func BenchmarkNeural(b *testing.B) {
+	benchmarkNeural(b, Neural)
+}
+
+// BenchmarkNeuralReal benchmarks neural mode with real weights
+func BenchmarkNeuralReal(b *testing.B) {
+	benchmarkNeural(b, NeuralReal)
+}
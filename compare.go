@@ -0,0 +1,101 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/stat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// Ranking is the scores of the nodes by a ranking method
+type Ranking struct {
+	Method string
+	Scores []float64
+}
+
+// Compare writes a table of the rank of each node by each method followed by the
+// Spearman and Kendall rank correlations and the L1 and L2 distances between the normalized
+// scores of every pair of methods
+func Compare(output io.Writer, labels []string, rankings []Ranking) {
+	positions := make([][]int, len(rankings))
+	for r, ranking := range rankings {
+		positions[r] = make([]int, len(ranking.Scores))
+		for i, node := range spectral.RankScores(ranking.Scores) {
+			positions[r][node] = i
+		}
+	}
+
+	methods := make([]string, len(rankings))
+	for r, ranking := range rankings {
+		methods[r] = ranking.Method
+	}
+	fmt.Fprintln(output, "node label", strings.Join(methods, " "))
+	for node, label := range labels {
+		fmt.Fprint(output, node, " ", label)
+		for r := range rankings {
+			fmt.Fprint(output, " ", positions[r][node])
+		}
+		fmt.Fprintf(output, "\n")
+	}
+
+	// the correlations compare the order of the nodes and the distances the relative weights
+	metrics := []struct {
+		Name   string
+		Metric func(x, y []float64) float64
+	}{
+		{"spearman", spectral.Spearman},
+		{"kendall", func(x, y []float64) float64 { return stat.Kendall(Quantize(x), Quantize(y), nil) }},
+		{"l1", func(x, y []float64) float64 { return floats.Distance(Unit(x), Unit(y), 1) }},
+		{"l2", func(x, y []float64) float64 { return floats.Distance(Unit(x), Unit(y), 2) }},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(output, "\n")
+		fmt.Fprintln(output, metric.Name, strings.Join(methods, " "))
+		for a := range rankings {
+			fmt.Fprint(output, methods[a])
+			for b := range rankings {
+				fmt.Fprintf(output, " %.4f", metric.Metric(rankings[a].Scores, rankings[b].Scores))
+			}
+			fmt.Fprintf(output, "\n")
+		}
+	}
+}
+
+// Quantize replaces every run of scores that RankScores ties with the largest score of the run
+// so that they are tied in the rank correlation
+func Quantize(scores []float64) []float64 {
+	quantized := append([]float64(nil), scores...)
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	for k := 1; k < len(order); k++ {
+		if math.Abs(scores[order[k-1]]-scores[order[k]]) <= spectral.Tolerance {
+			quantized[order[k]] = quantized[order[k-1]]
+		}
+	}
+	return quantized
+}
+
+// Unit scales the scores to unit L2 norm so that the distances between rankings only depend
+// on the relative weights of the nodes, zero scores are returned unchanged
+func Unit(scores []float64) []float64 {
+	unit := append([]float64(nil), scores...)
+	if norm := floats.Norm(unit, 2); norm > 0 {
+		floats.Scale(1/norm, unit)
+	}
+	return unit
+}
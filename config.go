@@ -0,0 +1,79 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Config is a json configuration file, the keys are flag names without the leading dash
+// and the values are json strings, numbers or booleans
+type Config map[string]interface{}
+
+// LoadConfig reads a json configuration file
+func LoadConfig(name string) (Config, error) {
+	input, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	decoder := json.NewDecoder(input)
+	decoder.UseNumber()
+	config := Config{}
+	err = decoder.Decode(&config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return config, nil
+}
+
+// Apply sets the flags from the configuration, flags that were set on the command line
+// take precedence over the configuration and the configuration takes precedence over
+// the flag defaults. Keys that aren't flags are reported in the error.
+func (c Config) Apply(flags *flag.FlagSet) error {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var unknown []string
+	for _, key := range keys {
+		if flags.Lookup(key) == nil || key == "config" {
+			unknown = append(unknown, key)
+			continue
+		}
+		if set[key] {
+			continue
+		}
+		var value string
+		switch v := c[key].(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("config %s: expected a string, number or boolean", key)
+		}
+		if err := flags.Set(key, value); err != nil {
+			return fmt.Errorf("config %s: invalid value %q: %v", key, value, err)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("config: unknown keys %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
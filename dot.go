@@ -0,0 +1,357 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DOTToken is a token in a DOT file
+type DOTToken struct {
+	Text   string
+	Quoted bool
+	Line   int
+}
+
+// DOTTokens splits a DOT file into tokens, comments are skipped
+func DOTTokens(input string) ([]DOTToken, error) {
+	var tokens []DOTToken
+	runes, line := []rune(input), 1
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' || (r == '/' && i+1 < len(runes) && runes[i+1] == '/'):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated comment", start)
+			}
+			i += 2
+		case r == '"':
+			start, text := line, strings.Builder{}
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '"' {
+					i++
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			i++
+			tokens = append(tokens, DOTToken{Text: text.String(), Quoted: true, Line: start})
+		case r == '-' && i+1 < len(runes) && (runes[i+1] == '>' || runes[i+1] == '-'):
+			tokens = append(tokens, DOTToken{Text: string(runes[i : i+2]), Line: line})
+			i += 2
+		case strings.ContainsRune("{}[];,=:", r):
+			tokens = append(tokens, DOTToken{Text: string(r), Line: line})
+			i++
+		case r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || runes[i] == '.' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				(i == start && runes[i] == '-')) {
+				i++
+			}
+			tokens = append(tokens, DOTToken{Text: string(runes[start:i]), Line: line})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, r)
+		}
+	}
+	return tokens, nil
+}
+
+// LoadDOT loads an adjacency matrix and the node names from a subset of the Graphviz DOT format,
+// node and edge statements are supported and edge weights are read from the weight attribute
+func LoadDOT(name string) (*mat.Dense, []string, bool, error) {
+	input, err := Open(name)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer input.Close()
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	tokens, err := DOTTokens(string(data))
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %v", name, err)
+	}
+
+	position := 0
+	peek := func() *DOTToken {
+		if position < len(tokens) {
+			return &tokens[position]
+		}
+		return nil
+	}
+	is := func(text string) bool {
+		token := peek()
+		return token != nil && !token.Quoted && strings.EqualFold(token.Text, text)
+	}
+	fail := func(format string, a ...interface{}) error {
+		line := 0
+		if position < len(tokens) {
+			line = tokens[position].Line
+		} else if len(tokens) > 0 {
+			line = tokens[len(tokens)-1].Line
+		}
+		return fmt.Errorf("%s: line %d: %s", name, line, fmt.Sprintf(format, a...))
+	}
+	expect := func(text string) error {
+		if !is(text) {
+			if token := peek(); token != nil {
+				return fail("expected %s, found %s", text, token.Text)
+			}
+			return fail("expected %s, found end of file", text)
+		}
+		position++
+		return nil
+	}
+	id := func() (string, error) {
+		token := peek()
+		if token == nil {
+			return "", fail("expected identifier, found end of file")
+		}
+		if !token.Quoted && (strings.ContainsAny(token.Text, "{}[];,=:") || token.Text == "->" || token.Text == "--") {
+			return "", fail("expected identifier, found %s", token.Text)
+		}
+		position++
+		return token.Text, nil
+	}
+	attributes := func() (map[string]string, error) {
+		values := make(map[string]string)
+		for is("[") {
+			position++
+			for !is("]") {
+				key, err := id()
+				if err != nil {
+					return nil, err
+				}
+				if is("=") {
+					position++
+					value, err := id()
+					if err != nil {
+						return nil, err
+					}
+					values[key] = value
+				}
+				if is(",") || is(";") {
+					position++
+				}
+			}
+			position++
+		}
+		return values, nil
+	}
+
+	if is("strict") {
+		position++
+	}
+	directed := false
+	switch {
+	case is("digraph"):
+		directed = true
+	case is("graph"):
+	default:
+		return nil, nil, false, fail("expected graph or digraph")
+	}
+	position++
+	if !is("{") {
+		if _, err := id(); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	if err := expect("{"); err != nil {
+		return nil, nil, false, err
+	}
+	operator := "--"
+	if directed {
+		operator = "->"
+	}
+
+	type Edge struct {
+		Source, Target int
+		Weight         float64
+	}
+	var (
+		names []string
+		edges []Edge
+	)
+	index := make(map[string]int)
+	node := func(name string) int {
+		if i, ok := index[name]; ok {
+			return i
+		}
+		index[name] = len(names)
+		names = append(names, name)
+		return index[name]
+	}
+	for !is("}") {
+		if peek() == nil {
+			return nil, nil, false, fail("expected }, found end of file")
+		}
+		if is(";") {
+			position++
+			continue
+		}
+		if is("subgraph") || is("{") {
+			return nil, nil, false, fail("subgraphs are not supported")
+		}
+		if is("graph") || is("node") || is("edge") {
+			position++
+			if _, err := attributes(); err != nil {
+				return nil, nil, false, err
+			}
+			continue
+		}
+		first, err := id()
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if is("=") {
+			position++
+			if _, err := id(); err != nil {
+				return nil, nil, false, err
+			}
+			continue
+		}
+		if is(":") {
+			return nil, nil, false, fail("ports are not supported")
+		}
+		chain := []int{node(first)}
+		for is("->") || is("--") {
+			if !is(operator) {
+				return nil, nil, false, fail("edge operator %s is not allowed, expected %s", peek().Text, operator)
+			}
+			position++
+			if is("subgraph") || is("{") {
+				return nil, nil, false, fail("subgraphs are not supported")
+			}
+			next, err := id()
+			if err != nil {
+				return nil, nil, false, err
+			}
+			chain = append(chain, node(next))
+		}
+		values, err := attributes()
+		if err != nil {
+			return nil, nil, false, err
+		}
+		weight := 1.0
+		if value, ok := values["weight"]; ok {
+			weight, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, nil, false, fail("weight: %v", err)
+			}
+		}
+		for i := 1; i < len(chain); i++ {
+			edges = append(edges, Edge{Source: chain[i-1], Target: chain[i], Weight: weight})
+		}
+	}
+	position++
+	if token := peek(); token != nil {
+		return nil, nil, false, fail("unexpected %s after graph", token.Text)
+	}
+	if len(names) == 0 {
+		return nil, nil, false, fmt.Errorf("%s: no nodes", name)
+	}
+
+	adjacency := mat.NewDense(len(names), len(names), nil)
+	for _, edge := range edges {
+		adjacency.Set(edge.Source, edge.Target, edge.Weight)
+		if !directed {
+			adjacency.Set(edge.Target, edge.Source, edge.Weight)
+		}
+	}
+	return adjacency, names, directed, nil
+}
+
+// WriteDOT writes the graph to a Graphviz DOT file, the nodes are labeled with their rank scores
+// and sized proportionally to them, the edges are labeled with their weights
+func WriteDOT(name string, adjacency *mat.Dense, labels []string, scores []float64, directed bool) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	max := 0.0
+	for _, score := range scores {
+		if math.Abs(score) > max {
+			max = math.Abs(score)
+		}
+	}
+	keyword, operator := "graph", "--"
+	if directed {
+		keyword, operator = "digraph", "->"
+	}
+
+	output := bufio.NewWriter(file)
+	fmt.Fprintf(output, "%s G {\n", keyword)
+	fmt.Fprintf(output, "  node [shape=circle, fixedsize=true];\n")
+	for i, label := range labels {
+		size := 0.5
+		if max > 0 {
+			size += 1.5 * math.Abs(scores[i]) / max
+		}
+		fmt.Fprintf(output, "  %s [label=%s, rank=%g, width=%.3f, height=%.3f];\n",
+			quote(strconv.Itoa(i)), quote(fmt.Sprintf("%s\\n%.4f", label, scores[i])), scores[i], size, size)
+	}
+	rows, cols := adjacency.Dims()
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			weight := adjacency.At(i, j)
+			if weight == 0 {
+				continue
+			}
+			// an undirected edge is written once, unless the matrix isn't symmetric and the
+			// weights of the two directions differ
+			if !directed && j < i && weight == adjacency.At(j, i) {
+				continue
+			}
+			// graphviz only accepts non-negative integer weights, so the weight is written as the
+			// label and only kept as the weight when it is one
+			attributes := fmt.Sprintf("label=%s", quote(strconv.FormatFloat(weight, 'g', -1, 64)))
+			if weight > 0 && weight == math.Trunc(weight) && weight <= math.MaxInt32 {
+				attributes += fmt.Sprintf(", weight=%d", int64(weight))
+			}
+			fmt.Fprintf(output, "  %s %s %s [%s];\n", quote(strconv.Itoa(i)), operator, quote(strconv.Itoa(j)), attributes)
+		}
+	}
+	fmt.Fprintf(output, "}\n")
+	return output.Flush()
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestWriteDOT(t *testing.T) {
+	cases := []struct {
+		name      string
+		adjacency *mat.Dense
+		directed  bool
+		expected  string
+	}{
+		// the fractional and negative weights are only labels, graphviz rejects them as weights
+		{"directed", mat.NewDense(3, 3, []float64{
+			0, 2, .5,
+			2, 0, 0,
+			-1, 0, 0,
+		}), true, `digraph G {
+  node [shape=circle, fixedsize=true];
+  "0" [label="a\n1.0000", rank=1, width=2.000, height=2.000];
+  "1" [label="b\n0.5000", rank=0.5, width=1.250, height=1.250];
+  "2" [label="c\n0.0000", rank=0, width=0.500, height=0.500];
+  "0" -> "1" [label="2", weight=2];
+  "0" -> "2" [label="0.5"];
+  "1" -> "0" [label="2", weight=2];
+  "2" -> "0" [label="-1"];
+}
+`},
+		{"undirected", KnownGraph("path"), false, `graph G {
+  node [shape=circle, fixedsize=true];
+  "0" [label="a\n1.0000", rank=1, width=2.000, height=2.000];
+  "1" [label="b\n0.5000", rank=0.5, width=1.250, height=1.250];
+  "2" [label="c\n0.0000", rank=0, width=0.500, height=0.500];
+  "0" -- "1" [label="1", weight=1];
+  "1" -- "2" [label="1", weight=1];
+}
+`},
+	}
+	for _, c := range cases {
+		name := filepath.Join(t.TempDir(), "graph.dot")
+		if err := WriteDOT(name, c.adjacency, []string{"a", "b", "c"}, []float64{1, .5, 0}, c.directed); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.expected {
+			t.Errorf("%s: dot\n%s\nexpected\n%s", c.name, data, c.expected)
+		}
+	}
+
+	// integer weights are kept as weights, so the graph reads back
+	name := filepath.Join(t.TempDir(), "graph.dot")
+	adjacency := mat.NewDense(3, 3, []float64{
+		0, 3, 1,
+		3, 0, 0,
+		1, 0, 0,
+	})
+	if err := WriteDOT(name, adjacency, []string{"a", "b", "c"}, []float64{1, .5, 0}, false); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _, directed, err := LoadDOT(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if directed || !mat.Equal(loaded, adjacency) {
+		t.Errorf("loaded directed %t\n%v\nexpected\n%v", directed, mat.Formatted(loaded), mat.Formatted(adjacency))
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Writable checks that a file can be created in the directory of name
+func Writable(name string) error {
+	if err := WritableDirectory(filepath.Dir(name)); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// WritableDirectory checks that a file can be created in the directory
+func WritableDirectory(directory string) error {
+	file, err := ioutil.TempFile(directory, ".truther-")
+	if err != nil {
+		return fmt.Errorf("directory %s isn't writable: %v", directory, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// OutputDirectory checks that the output directory is writable or can be created
+func OutputDirectory(plan func(format string, a ...interface{})) error {
+	directory := *FlagOutputDir
+	if directory == "" {
+		return WritableDirectory(".")
+	}
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		parent := filepath.Dir(filepath.Clean(directory))
+		for {
+			if _, err := os.Stat(parent); err == nil || filepath.Dir(parent) == parent {
+				break
+			}
+			parent = filepath.Dir(parent)
+		}
+		if err := WritableDirectory(parent); err != nil {
+			return err
+		}
+		plan("create the output directory %s", directory)
+		return nil
+	}
+	return WritableDirectory(directory)
+}
+
+// DryRun validates the flags, the input and the output paths and writes the steps that
+// would run to output without running them, nothing is written if the validation fails
+func DryRun(output io.Writer) error {
+	if err := ValidateFlags(); err != nil {
+		return err
+	}
+	var steps bytes.Buffer
+	if err := Plan(&steps); err != nil {
+		return err
+	}
+	_, err := output.Write(steps.Bytes())
+	return err
+}
+
+// Plan writes the steps that would run to output as it checks the input and the output paths,
+// the flags are checked with ValidateFlags before
+func Plan(output io.Writer) error {
+	step := 0
+	plan := func(format string, a ...interface{}) {
+		step++
+		fmt.Fprintf(output, "%d %s\n", step, fmt.Sprintf(format, a...))
+	}
+
+	if *FlagStream {
+		plan("read edges from stdin until it ends")
+		var when []string
+		if *FlagInterval > 0 {
+			when = append(when, fmt.Sprintf("every %gs", *FlagInterval))
+		}
+		if *FlagStreamEvery > 0 {
+			when = append(when, fmt.Sprintf("every %d edges", *FlagStreamEvery))
+		}
+		when = append(when, "at the end")
+		plan("rank the nodes with power iteration started from the previous ranking %s", strings.Join(when, ", "))
+		return nil
+	}
+	if *FlagInputDir != "" {
+		if _, err := ioutil.ReadDir(*FlagInputDir); err != nil {
+			return err
+		}
+		if err := OutputDirectory(plan); err != nil {
+			return err
+		}
+		plan("analyze the graphs in %s with %d workers, writing a .out file for each", *FlagInputDir, *FlagWorkers)
+		return nil
+	}
+
+	if *FlagComplexInput != "" {
+		if _, err := LoadComplexCSV(*FlagComplexInput); err != nil {
+			return err
+		}
+		plan("load the complex csv %s", *FlagComplexInput)
+		plan("eigendecompose the complex matrix")
+		plan("rank the nodes by the magnitude of the dominant eigenvector")
+		if *FlagEigenOutput != "" {
+			if err := Writable(*FlagEigenOutput); err != nil {
+				return err
+			}
+			plan("write the eigendecomposition to %s", *FlagEigenOutput)
+		}
+		return OutputDirectory(plan)
+	}
+
+	var outputs []string
+	writes := func(name string) {
+		if name != "" {
+			outputs = append(outputs, name)
+		}
+	}
+	writes(*FlagProfile)
+	writes(*FlagManifest)
+
+	loaded := false
+	if format, name := InputSource(); name != "" {
+		if name != "-" {
+			input, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			input.Close()
+		}
+		plan("load the %s %s", format, name)
+		loaded = true
+	}
+	if !loaded && *FlagGenerate != "" {
+		options := GenerateFlags()
+		if !*FlagPower {
+			if err := CheckSize(options.Size, *FlagMaxSize); err != nil {
+				return err
+			}
+		}
+		if _, err := Generate(rand.New(rand.NewSource(1)), options); err != nil {
+			return err
+		}
+		plan("generate a random graph: %s, seed %d", options, *FlagSeed)
+	} else if !loaded {
+		plan("generate the %d node demo graph", *FlagSize)
+	}
+	if *FlagLabels != "" {
+		if _, err := os.Stat(*FlagLabels); err != nil {
+			return err
+		}
+	}
+	if *FlagMetadata != "" {
+		if _, err := os.Stat(*FlagMetadata); err != nil {
+			return err
+		}
+	}
+
+	plan("validate the adjacency matrix")
+	if !*FlagSelfLoops {
+		plan("strip the self-loops")
+	}
+	if *FlagThreshold > 0 {
+		plan("prune the edges below %g", *FlagThreshold)
+	}
+	if *FlagNormalize != "" && *FlagNormalize != "none" {
+		plan("normalize with %s", *FlagNormalize)
+	}
+	if *FlagLaplacian {
+		plan("take the laplacian")
+	}
+	if *FlagSigned {
+		plan("print the structural balance and take the signed laplacian")
+	}
+
+	if *FlagHeatMap != "" {
+		name := HeatMapName(*FlagHeatMap, "adjacency")
+		writes(name)
+		plan("save the heat map of the adjacency matrix to %s", name)
+	}
+
+	if *FlagBipartite {
+		plan("rank the partitions of the bipartite graph with the singular value decomposition")
+	} else if *FlagPower {
+		plan("power iteration for at most %d iterations", *FlagPowerIterations)
+		plan("rank the nodes")
+		writes(*FlagRankingOutput)
+		if *FlagDOTOutput != "" {
+			writes(*FlagDOTOutput)
+			plan("write the ranked graph to %s", *FlagDOTOutput)
+		}
+	} else {
+		plan("eigendecompose with the %s eigenvectors", *FlagEigenSide)
+		if *FlagEigenOutput != "" {
+			writes(*FlagEigenOutput)
+			plan("write the eigendecomposition to %s", *FlagEigenOutput)
+		}
+		plan("rank the nodes by the dominant eigenvector and eigenvector centrality")
+		if *FlagKatz {
+			plan("rank the nodes by katz centrality with alpha %g", *FlagKatzAlpha)
+		}
+		if *FlagHITS {
+			plan("rank the hubs and authorities")
+		}
+		if *FlagPageRank {
+			plan("rank the nodes by page rank with damping %g", *FlagDamping)
+		}
+		writes(*FlagRankingOutput)
+		if *FlagCommunities {
+			plan("detect the communities with the modularity matrix")
+		}
+		if *FlagFiedler {
+			plan("bisect the graph with the fiedler vector")
+		}
+		if *FlagCompare {
+			writes(*FlagCompareOutput)
+			plan("compare the rankings")
+		}
+		writes(*FlagPhasePlot)
+		if *FlagDOTOutput != "" {
+			writes(*FlagDOTOutput)
+			plan("write the ranked graph to %s", *FlagDOTOutput)
+		}
+		if *FlagNeural {
+			if *FlagLoadWeights != "" {
+				if _, err := os.Stat(*FlagLoadWeights); err != nil {
+					return err
+				}
+				plan("load the %d layer neural weights from %s", *FlagLayers, *FlagLoadWeights)
+			} else {
+				plan("train a %d layer neural network with %s for at most %d epochs", *FlagLayers, *FlagOptimizer, *FlagIterations)
+				if *FlagInit != "random" {
+					plan("initialize the first layer from the %s matrix", *FlagInit)
+				}
+				if *FlagRestarts > 1 {
+					plan("keep the lowest cost of %d training restarts", *FlagRestarts)
+				}
+				if *FlagValSplit > 0 {
+					plan("hold out %g of the eigenvectors for validation", *FlagValSplit)
+				}
+				writes(*FlagSaveWeights)
+				writes(*FlagCostPlot)
+				writes(*FlagCostData)
+				if *FlagAnimate != "" {
+					plan("save a heat map of the first layer to %s00000.png every %d epochs", *FlagAnimate, *FlagAnimateEvery)
+				}
+			}
+			writes(*FlagLearnedOutput)
+			writes(*FlagGradientOutput)
+			writes(HeatMapName(*FlagHeatMap, "learned"))
+		}
+		if *FlagPCAWeights != "" {
+			if _, err := os.Stat(*FlagPCAWeights); err != nil {
+				return err
+			}
+		}
+		if *FlagHeatT > 0 {
+			plan("embed the nodes with the heat kernel exp(-%g L) of the laplacian", *FlagHeatT)
+		}
+		plan("project onto %d principal components of the %s matrix", *FlagComponents, *FlagPCAMatrix)
+		if *FlagClusters > 0 {
+			plan("cluster the projection into %d clusters", *FlagClusters)
+		}
+		if *FlagComponents == 2 || *FlagComponents == 3 {
+			writes(*FlagVectorsPlot)
+		}
+		writes(*FlagVectorsData)
+		writes(*FlagVectorsJSON)
+		if *FlagSimilarity != "" {
+			plan("compute the cosine similarity of the projected nodes")
+			writes(*FlagSimilarity)
+			writes(HeatMapName(*FlagHeatMap, "similarity"))
+		}
+	}
+
+	if err := OutputDirectory(plan); err != nil {
+		return err
+	}
+	for _, name := range outputs {
+		// the files in an output directory that doesn't exist yet are checked by its parent
+		if _, err := os.Stat(*FlagOutputDir); os.IsNotExist(err) && *FlagOutputDir != "" &&
+			filepath.Dir(name) == filepath.Clean(*FlagOutputDir) {
+			continue
+		}
+		if err := Writable(name); err != nil {
+			return err
+		}
+	}
+	if len(outputs) > 0 {
+		plan("write %v", outputs)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Models are the random graph models of -generate
+var Models = []string{"er", "ba", "ws"}
+
+// GenerateOptions are the parameters of the random graph models
+type GenerateOptions struct {
+	// Model is one of Models
+	Model string
+	// Size is the number of nodes
+	Size int
+	// P is the edge probability for er and the rewiring probability for ws
+	P float64
+	// M is the number of edges each new node attaches with for ba
+	M int
+	// K is the number of ring neighbors of each node for ws
+	K int
+}
+
+// String describes the model and the parameters it uses
+func (o GenerateOptions) String() string {
+	switch o.Model {
+	case "er":
+		return fmt.Sprintf("erdos-renyi size %d p %g", o.Size, o.P)
+	case "ba":
+		return fmt.Sprintf("barabasi-albert size %d m %d", o.Size, o.M)
+	case "ws":
+		return fmt.Sprintf("watts-strogatz size %d k %d p %g", o.Size, o.K, o.P)
+	}
+	return o.Model
+}
+
+// Generate builds a random undirected graph without self-loops
+func Generate(rng *rand.Rand, options GenerateOptions) (*mat.Dense, error) {
+	size := options.Size
+	if size < 2 {
+		return nil, fmt.Errorf("generated graphs need at least 2 nodes, got size %d", size)
+	}
+	if options.P < 0 || options.P > 1 {
+		return nil, fmt.Errorf("probability %g must be between 0 and 1", options.P)
+	}
+	adjacency := mat.NewDense(size, size, nil)
+	connect := func(i, j int) {
+		adjacency.Set(i, j, 1)
+		adjacency.Set(j, i, 1)
+	}
+	switch options.Model {
+	case "er":
+		for i := 0; i < size; i++ {
+			for j := i + 1; j < size; j++ {
+				if rng.Float64() < options.P {
+					connect(i, j)
+				}
+			}
+		}
+	case "ba":
+		m := options.M
+		if m < 1 || m >= size {
+			return nil, fmt.Errorf("barabasi-albert m %d must be between 1 and %d", m, size-1)
+		}
+		// the graph starts as a clique of m+1 nodes, ends holds the endpoints of every edge so
+		// that sampling from it picks nodes in proportion to their degree
+		ends := make([]int, 0, 2*m*size)
+		for i := 0; i <= m; i++ {
+			for j := i + 1; j <= m; j++ {
+				connect(i, j)
+				ends = append(ends, i, j)
+			}
+		}
+		for node := m + 1; node < size; node++ {
+			targets := make(map[int]bool, m)
+			for len(targets) < m {
+				targets[ends[rng.Intn(len(ends))]] = true
+			}
+			for target := 0; target < node; target++ {
+				if targets[target] {
+					connect(node, target)
+					ends = append(ends, node, target)
+				}
+			}
+		}
+	case "ws":
+		k := options.K
+		if k < 2 || k%2 != 0 || k >= size {
+			return nil, fmt.Errorf("watts-strogatz k %d must be even and between 2 and %d", k, size-1)
+		}
+		for i := 0; i < size; i++ {
+			for j := 1; j <= k/2; j++ {
+				connect(i, (i+j)%size)
+			}
+		}
+		for j := 1; j <= k/2; j++ {
+			for i := 0; i < size; i++ {
+				neighbor := (i + j) % size
+				if adjacency.At(i, neighbor) == 0 || rng.Float64() >= options.P {
+					continue
+				}
+				// a node connected to every other node can't be rewired
+				degree := 0
+				for n := 0; n < size; n++ {
+					degree += int(adjacency.At(i, n))
+				}
+				if degree == size-1 {
+					continue
+				}
+				target := rng.Intn(size)
+				for target == i || adjacency.At(i, target) != 0 {
+					target = rng.Intn(size)
+				}
+				adjacency.Set(i, neighbor, 0)
+				adjacency.Set(neighbor, i, 0)
+				connect(i, target)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown model %s, supported models are %s", options.Model, strings.Join(Models, ", "))
+	}
+	return adjacency, nil
+}
@@ -0,0 +1,487 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// Open opens the named file for reading, "-" is standard input
+func Open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// LoadCSV loads a square adjacency matrix from a csv file, "-" reads from standard input
+func LoadCSV(name string) (*mat.Dense, error) {
+	input, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	reader := csv.NewReader(input)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := len(records)
+	if rows == 0 {
+		return nil, fmt.Errorf("%s: no rows", name)
+	}
+	data := make([]float64, 0, rows*rows)
+	for i, record := range records {
+		if len(record) != rows {
+			return nil, fmt.Errorf("%s: row %d has %d columns, expected %d for a square matrix", name, i+1, len(record), rows)
+		}
+		for j, field := range record {
+			value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: row %d column %d: %v", name, i+1, j+1, err)
+			}
+			data = append(data, value)
+		}
+	}
+	return mat.NewDense(rows, rows, data), nil
+}
+
+// LoadComplexCSV loads a complex matrix from a csv file, each row has a real and an imaginary
+// column for every entry
+func LoadComplexCSV(name string) (*mat.CDense, error) {
+	input, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	reader := csv.NewReader(input)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := len(records)
+	if rows == 0 {
+		return nil, fmt.Errorf("%s: no rows", name)
+	}
+	data := make([]complex128, 0, rows*rows)
+	for i, record := range records {
+		if len(record) != 2*rows {
+			return nil, fmt.Errorf("%s: row %d has %d columns, expected %d for a re,im pair per entry of a square matrix", name, i+1, len(record), 2*rows)
+		}
+		for j := 0; j < len(record); j += 2 {
+			re, err := strconv.ParseFloat(strings.TrimSpace(record[j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: row %d column %d: %v", name, i+1, j+1, err)
+			}
+			im, err := strconv.ParseFloat(strings.TrimSpace(record[j+1]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: row %d column %d: %v", name, i+1, j+2, err)
+			}
+			data = append(data, complex(re, im))
+		}
+	}
+	return mat.NewCDense(rows, rows, data), nil
+}
+
+// ParseEdge parses a line of a whitespace separated edge list with an optional weight column, it
+// returns false for blank lines and comments, which start with #
+func ParseEdge(line string) (spectral.Edge, bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return spectral.Edge{}, false, nil
+	}
+	if len(fields) != 2 && len(fields) != 3 {
+		return spectral.Edge{}, false, fmt.Errorf("%d fields, expected 2 or 3", len(fields))
+	}
+	var err error
+	edge := spectral.Edge{Weight: 1}
+	edge.Source, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return spectral.Edge{}, false, err
+	}
+	edge.Destination, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return spectral.Edge{}, false, err
+	}
+	if edge.Source < 0 || edge.Destination < 0 {
+		return spectral.Edge{}, false, errors.New("negative node id")
+	}
+	if len(fields) == 3 {
+		edge.Weight, err = strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return spectral.Edge{}, false, err
+		}
+	}
+	return edge, true, nil
+}
+
+// ReadEdgeList reads the edges of a whitespace separated edge list file with an optional weight
+// column, returning the edges and the number of nodes
+func ReadEdgeList(name string) ([]spectral.Edge, int, error) {
+	input, err := Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer input.Close()
+
+	edges, size, line := make([]spectral.Edge, 0, 8), 0, 0
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line++
+		edge, ok, err := ParseEdge(scanner.Text())
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: line %d: %v", name, line, err)
+		}
+		if !ok {
+			continue
+		}
+		if edge.Source+1 > size {
+			size = edge.Source + 1
+		}
+		if edge.Destination+1 > size {
+			size = edge.Destination + 1
+		}
+		edges = append(edges, edge)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	if size == 0 {
+		return nil, 0, fmt.Errorf("%s: no edges", name)
+	}
+	return edges, size, nil
+}
+
+// ErrTooLarge is returned for graphs with more nodes than -max-size
+var ErrTooLarge = errors.New("the graph is too large for the dense eigendecomposition")
+
+// CheckSize returns ErrTooLarge if the graph has more than max nodes, the dense
+// eigendecomposition takes O(n³) time and O(n²) memory. A max of 0 disables the limit.
+func CheckSize(size, max int) error {
+	if max > 0 && size > max {
+		return fmt.Errorf("%w: %d nodes is above -max-size %d, raise the limit or set it to 0 to disable it", ErrTooLarge, size, max)
+	}
+	return nil
+}
+
+// LoadEdgeList loads an adjacency matrix from a whitespace separated edge list,
+// undirected edges are mirrored and "-" reads from standard input. Graphs with more than
+// maxSize nodes are refused with CheckSize before the matrix is allocated.
+func LoadEdgeList(name string, directed bool, maxSize int) (*mat.Dense, error) {
+	edges, size, err := ReadEdgeList(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSize(size, maxSize); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	adjacency := mat.NewDense(size, size, nil)
+	for _, edge := range edges {
+		adjacency.Set(edge.Source, edge.Destination, edge.Weight)
+		if !directed {
+			adjacency.Set(edge.Destination, edge.Source, edge.Weight)
+		}
+	}
+	return adjacency, nil
+}
+
+// LoadEdgeListSparse loads a sparse adjacency matrix from an edge list file
+func LoadEdgeListSparse(name string, directed bool) (*spectral.CSR, error) {
+	edges, size, err := ReadEdgeList(name)
+	if err != nil {
+		return nil, err
+	}
+	return spectral.NewCSR(size, edges, directed), nil
+}
+
+// LoadLabels loads the node names from a file with one name per line, nodes without
+// a name are labeled with their index
+func LoadLabels(name string, size int) ([]string, error) {
+	labels := make([]string, size)
+	for i := range labels {
+		labels[i] = strconv.Itoa(i)
+	}
+	if name == "" {
+		return labels, nil
+	}
+
+	input, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	scanner, i := bufio.NewScanner(input), 0
+	for scanner.Scan() && i < size {
+		if label := strings.TrimSpace(scanner.Text()); label != "" {
+			labels[i] = label
+		}
+		i++
+	}
+	return labels, scanner.Err()
+}
+
+// LoadPCAWeights loads a weight per line for each of the size nodes, blank lines are skipped.
+// The weights can't be negative and at least one has to be positive.
+func LoadPCAWeights(name string, size int) ([]float64, error) {
+	input, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	weights, line, total := make([]float64, 0, size), 0, 0.0
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		weight, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", name, line, err)
+		}
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("%s: line %d: weight %g must be finite and non-negative", name, line, weight)
+		}
+		weights = append(weights, weight)
+		total += weight
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(weights) != size {
+		return nil, fmt.Errorf("%s: %d weights for %d nodes", name, len(weights), size)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%s: every weight is 0", name)
+	}
+	return weights, nil
+}
+
+// Metadata are attribute columns of the nodes, Rows[node] are the attributes of a node and
+// nodes without a row have no attributes
+type Metadata struct {
+	Columns []string
+	Rows    map[int][]string
+}
+
+// Attributes returns the attributes of the node, blank if the node has no row
+func (m *Metadata) Attributes(node int) []string {
+	if row, ok := m.Rows[node]; ok {
+		return row
+	}
+	return make([]string, len(m.Columns))
+}
+
+// LoadMetadata loads the node attributes from a csv file with a header row, the first column is
+// the node key and the others are the attributes. A key is the name of a node in labels or else
+// its index, every key must be a node of the graph and appear once. It returns nil if the name
+// is empty.
+func LoadMetadata(name string, labels []string) (*Metadata, error) {
+	if name == "" {
+		return nil, nil
+	}
+	input, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	reader := csv.NewReader(input)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || len(records[0]) < 2 {
+		return nil, fmt.Errorf("%s: the header needs a node key column and at least one attribute column", name)
+	}
+
+	nodes := make(map[string]int, len(labels))
+	for node, label := range labels {
+		nodes[label] = node
+	}
+	metadata := &Metadata{Columns: records[0][1:], Rows: make(map[int][]string, len(records)-1)}
+	for i, record := range records[1:] {
+		key := strings.TrimSpace(record[0])
+		node, ok := nodes[key]
+		if !ok {
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(labels) {
+				return nil, fmt.Errorf("%s: row %d: %q isn't the name or index of a node of the graph", name, i+2, key)
+			}
+			node = index
+		}
+		if _, ok := metadata.Rows[node]; ok {
+			return nil, fmt.Errorf("%s: row %d: node %q has more than one row", name, i+2, key)
+		}
+		metadata.Rows[node] = record[1:]
+	}
+	return metadata, nil
+}
+
+// LoadGraphML loads an adjacency matrix and the node names from a GraphML file, edge weights
+// are read from the edge key named weight and the graph is directed if any edge is directed
+func LoadGraphML(name string) (*mat.Dense, []string, bool, error) {
+	input, err := Open(name)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer input.Close()
+
+	type Data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type Unsupported struct{}
+	type Node struct {
+		ID     string        `xml:"id,attr"`
+		Graphs []Unsupported `xml:"graph"`
+		Ports  []Unsupported `xml:"port"`
+	}
+	type Edge struct {
+		Source   string `xml:"source,attr"`
+		Target   string `xml:"target,attr"`
+		Directed string `xml:"directed,attr"`
+		Data     []Data `xml:"data"`
+	}
+	type Graph struct {
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []Node        `xml:"node"`
+		Edges       []Edge        `xml:"edge"`
+		HyperEdges  []Unsupported `xml:"hyperedge"`
+	}
+	type Key struct {
+		ID      string `xml:"id,attr"`
+		For     string `xml:"for,attr"`
+		Name    string `xml:"attr.name,attr"`
+		Default string `xml:"default"`
+	}
+	type GraphML struct {
+		XMLName xml.Name `xml:"graphml"`
+		Keys    []Key    `xml:"key"`
+		Graphs  []Graph  `xml:"graph"`
+	}
+
+	var document GraphML
+	err = xml.NewDecoder(input).Decode(&document)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(document.Graphs) != 1 {
+		return nil, nil, false, fmt.Errorf("%s: expected 1 graph, found %d", name, len(document.Graphs))
+	}
+	graph := document.Graphs[0]
+	if len(graph.HyperEdges) > 0 {
+		return nil, nil, false, fmt.Errorf("%s: hyperedges are not supported", name)
+	}
+
+	weightKey, weightDefault := "", 1.0
+	for _, key := range document.Keys {
+		if (key.For == "edge" || key.For == "all") && strings.EqualFold(key.Name, "weight") {
+			weightKey = key.ID
+			if value := strings.TrimSpace(key.Default); value != "" {
+				weightDefault, err = strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, nil, false, fmt.Errorf("%s: key %s default: %v", name, key.ID, err)
+				}
+			}
+		}
+	}
+
+	names, index := make([]string, 0, len(graph.Nodes)), make(map[string]int, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		if len(node.Graphs) > 0 {
+			return nil, nil, false, fmt.Errorf("%s: nested graphs are not supported", name)
+		}
+		if len(node.Ports) > 0 {
+			return nil, nil, false, fmt.Errorf("%s: ports are not supported", name)
+		}
+		if _, ok := index[node.ID]; ok {
+			return nil, nil, false, fmt.Errorf("%s: duplicate node %s", name, node.ID)
+		}
+		index[node.ID] = len(names)
+		names = append(names, node.ID)
+	}
+	if len(names) == 0 {
+		return nil, nil, false, fmt.Errorf("%s: no nodes", name)
+	}
+
+	adjacency, directed := mat.NewDense(len(names), len(names), nil), false
+	for _, edge := range graph.Edges {
+		source, ok := index[edge.Source]
+		if !ok {
+			return nil, nil, false, fmt.Errorf("%s: edge source %s is not a node", name, edge.Source)
+		}
+		target, ok := index[edge.Target]
+		if !ok {
+			return nil, nil, false, fmt.Errorf("%s: edge target %s is not a node", name, edge.Target)
+		}
+		weight := weightDefault
+		for _, data := range edge.Data {
+			if weightKey == "" || data.Key != weightKey {
+				continue
+			}
+			weight, err = strconv.ParseFloat(strings.TrimSpace(data.Value), 64)
+			if err != nil {
+				return nil, nil, false, fmt.Errorf("%s: edge %s %s weight: %v", name, edge.Source, edge.Target, err)
+			}
+		}
+		isDirected := graph.EdgeDefault == "directed"
+		switch edge.Directed {
+		case "true":
+			isDirected = true
+		case "false":
+			isDirected = false
+		}
+		adjacency.Set(source, target, weight)
+		if isDirected {
+			directed = true
+		} else {
+			adjacency.Set(target, source, weight)
+		}
+	}
+	return adjacency, names, directed, nil
+}
+
+// Demo returns the built in demo adjacency matrix
+func Demo(size int) (*mat.Dense, error) {
+	data := []float64{
+		0, 1, 0, 1, 1,
+		1, 0, 1, 0, 1,
+		0, 1, 0, 1, 1,
+		1, 0, 1, 0, 1,
+		1, 1, 1, 1, 1,
+	}
+	if len(data) != size*size {
+		return nil, fmt.Errorf("adjacency matrix has %d entries, expected %d for size %d", len(data), size*size, size)
+	}
+	return mat.NewDense(size, size, data), nil
+}
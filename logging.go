@@ -0,0 +1,75 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// The log levels of -v, each level includes the levels below it
+const (
+	// LevelError only prints errors
+	LevelError = iota
+	// LevelWarning also prints warnings such as validation issues
+	LevelWarning
+	// LevelInfo also prints the results such as the rankings
+	LevelInfo
+	// LevelDebug also prints the eigendecomposition, weight and projection dumps
+	LevelDebug
+)
+
+// Logger prints messages up to its level, warnings go to Errors and the other messages to Output
+type Logger struct {
+	Level  int
+	Output io.Writer
+	Errors io.Writer
+}
+
+// Log is the logger of the command, its level is set by -v
+var Log = &Logger{Level: LevelInfo, Output: os.Stdout, Errors: os.Stderr}
+
+// Enabled returns true if messages at level are printed
+func (l *Logger) Enabled(level int) bool {
+	return level <= l.Level
+}
+
+// Writer returns the writer for messages at level, which discards them if the level isn't enabled
+func (l *Logger) Writer(level int) io.Writer {
+	if !l.Enabled(level) {
+		return ioutil.Discard
+	}
+	if level <= LevelWarning {
+		return l.Errors
+	}
+	return l.Output
+}
+
+// Warnf prints a warning, the message is prefixed with "warning: " and ends with a newline
+func (l *Logger) Warnf(format string, a ...interface{}) {
+	fmt.Fprintf(l.Writer(LevelWarning), "warning: "+format+"\n", a...)
+}
+
+// Infof prints a formatted info message
+func (l *Logger) Infof(format string, a ...interface{}) {
+	fmt.Fprintf(l.Writer(LevelInfo), format, a...)
+}
+
+// Infoln prints an info message followed by a newline
+func (l *Logger) Infoln(a ...interface{}) {
+	fmt.Fprintln(l.Writer(LevelInfo), a...)
+}
+
+// Debugf prints a formatted debug message
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	fmt.Fprintf(l.Writer(LevelDebug), format, a...)
+}
+
+// Debugln prints a debug message followed by a newline
+func (l *Logger) Debugln(a ...interface{}) {
+	fmt.Fprintln(l.Writer(LevelDebug), a...)
+}
@@ -5,184 +5,1452 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"math/cmplx"
 	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
 
 	"gonum.org/v1/gonum/mat"
-	"gonum.org/v1/gonum/stat"
 	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/palette/moreland"
 	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 
 	"github.com/pointlander/gradient/tc128"
-)
 
-const (
-	// Size is the size of the square matrix
-	Size = 5
+	"github.com/pointlander/truther/spectral"
 )
 
 var (
+	// FlagConfig is a json file of flag values, flags on the command line override it
+	FlagConfig = flag.String("config", "", "json file of flag values keyed by flag name, flags on the command line override it")
+	// FlagOutputDir is the directory the output files are written to
+	FlagOutputDir = flag.String("output-dir", "", "directory the output files with relative names are written to, it is created if it doesn't exist")
+	// FlagDryRun validates the flags, input and output paths and prints the plan without running it
+	FlagDryRun = flag.Bool("dry-run", false, "validate the flags, the input and the output paths and print the steps that would run without running them")
+	// FlagTiming prints the duration of each phase of the run
+	FlagTiming = flag.Bool("timing", false, "print the duration of each phase of the run to stderr")
+	// FlagProfile is the file the cpu profile is written to
+	FlagProfile = flag.String("profile", "", "file the pprof cpu profile is written to")
+	// FlagStrict fails on invalid adjacency matrices instead of warning
+	FlagStrict = flag.Bool("strict", false, "fail if the adjacency matrix is negative, asymmetric for an undirected graph or has self-loops that aren't allowed")
+	// FlagThreshold prunes the edges with a weight below it
+	FlagThreshold = flag.Float64("threshold", 0, "prune the edges whose weight magnitude is below the threshold before the analysis")
+	// FlagSelfLoops keeps self-loops in the adjacency matrix
+	FlagSelfLoops = flag.Bool("self-loops", true, "keep self-loops in the adjacency matrix, false zeroes the diagonal before the analysis")
 	// FlagNeural neural mode
 	FlagNeural = flag.Bool("neural", false, "neural mode")
+	// FlagLayers is the number of layers in neural mode
+	FlagLayers = flag.Int("layers", 1, "number of layers in neural mode, layers are joined by a tanh activation")
+	// FlagClip is the gradient norm threshold for clipping in neural mode
+	FlagClip = flag.Float64("clip", 1, "gradient norm above which the gradient is scaled down in neural mode, 0 disables clipping")
+	// FlagBatch is the number of eigenvectors in a minibatch
+	FlagBatch = flag.Int("batch", 0, "number of eigenvectors sampled for each epoch of neural mode, 0 uses all of them")
+	// FlagRestarts is the number of neural training runs from different random weights
+	FlagRestarts = flag.Int("restarts", 1, "number of neural training runs from different random weights, the weights with the lowest final cost are kept")
+	// FlagValSplit is the fraction of eigenvectors held out from neural training
+	FlagValSplit = flag.Float64("val-split", 0, "fraction of the eigenvectors held out from neural training to report the validation cost, needs more than one eigenvector, 0 trains on all of them")
+	// FlagReal uses real weights in neural mode
+	FlagReal = flag.Bool("real", false, "use real weights in neural mode, requires a symmetric graph")
+	// FlagMaxSize is the largest graph that is eigendecomposed
+	FlagMaxSize = flag.Int("max-size", 5000, "largest number of nodes for the dense eigendecomposition, which takes O(n³) time and O(n²) memory, larger graphs need -power, 0 disables the limit")
+	// FlagSize is the size of the square matrix
+	FlagSize = flag.Int("size", 5, "size of the square matrix")
+	// FlagGenerate is the random graph model to generate instead of reading input
+	FlagGenerate = flag.String("generate", "", "generate a random graph with -size nodes and -seed instead of reading input: er for erdos-renyi, ba for barabasi-albert or ws for watts-strogatz")
+	// FlagGrP is the edge probability of er and the rewiring probability of ws
+	FlagGrP = flag.Float64("gr-p", .1, "edge probability of the er model and rewiring probability of the ws model")
+	// FlagGrM is the number of edges each new node attaches with in the ba model
+	FlagGrM = flag.Int("gr-m", 2, "number of edges each new node attaches with in the ba model")
+	// FlagGrK is the number of ring neighbors of each node in the ws model
+	FlagGrK = flag.Int("gr-k", 4, "number of ring neighbors of each node in the ws model, must be even")
+	// FlagInputDir is a directory of graph files to analyze
+	FlagInputDir = flag.String("input-dir", "", "directory of .csv, .el, .graphml and .dot graph files to analyze, the results are written to <file>.out")
+	// FlagWorkers is the number of graphs analyzed concurrently
+	FlagWorkers = flag.Int("workers", runtime.GOMAXPROCS(0), "number of graphs from -input-dir analyzed concurrently")
+	// FlagStream ranks a stream of edges read from stdin
+	FlagStream = flag.Bool("stream", false, "read edge list lines from stdin until it ends and print the top -top-k nodes of the power iteration ranking every -interval seconds and every -stream-every edges")
+	// FlagInterval is the number of seconds between the rankings of -stream
+	FlagInterval = flag.Float64("interval", 1, "seconds between the rankings of -stream, 0 disables the timer")
+	// FlagStreamEvery is the number of edges between the rankings of -stream
+	FlagStreamEvery = flag.Int("stream-every", 0, "number of edges between the rankings of -stream, 0 disables the count")
+	// FlagInput is a csv file containing the adjacency matrix
+	FlagInput = flag.String("input", "", "csv file containing the adjacency matrix, - reads from standard input")
+	// FlagComplexInput is the csv file containing a complex adjacency matrix
+	FlagComplexInput = flag.String("complex-input", "", "csv file containing a complex adjacency matrix with a re,im column pair for each entry")
+	// FlagEdgeList is an edge list file containing the graph
+	FlagEdgeList = flag.String("edgelist", "", "edge list file containing the graph, - reads from standard input")
+	// FlagGraphML is a GraphML file containing the graph
+	FlagGraphML = flag.String("graphml", "", "GraphML file containing the graph, - reads from standard input")
+	// FlagDOT is a Graphviz DOT file containing the graph
+	FlagDOT = flag.String("dot", "", "Graphviz DOT file containing the graph, - reads from standard input")
+	// FlagDOTOutput is the Graphviz DOT file the ranked graph is written to
+	FlagDOTOutput = flag.String("dot-output", "", "Graphviz DOT file the ranked graph is written to, page rank scores are used with -pagerank")
+	// FlagManifest is the json file recording how the results were produced
+	FlagManifest = flag.String("manifest", "", "json file recording the flags, the seed, the sha-256 of the input file and of the adjacency matrix and the version so that the analysis of a graph can be reproduced, its flags can be passed to -config")
+	// FlagRankingOutput is the csv file the rankings are written to
+	FlagRankingOutput = flag.String("ranking-output", "", "csv file the rankings of every node are written to sorted by rank: the spectral ranking followed by katz with -katz and page rank with -pagerank, or the power iteration ranking with -power")
+	// FlagMetadata is a csv file of node attributes joined into the ranking output
+	FlagMetadata = flag.String("metadata", "", "csv file of node attributes with a header row, keyed by node name or index in the first column, which are joined into -ranking-output")
+	// FlagDirected the graph is directed
+	FlagDirected = flag.Bool("directed", false, "the graph is directed")
+	// FlagEta is the learning rate for neural mode
+	FlagEta = flag.Float64("eta", .3, "learning rate for neural mode")
+	// FlagIterations is the number of iterations for neural mode
+	FlagIterations = flag.Int("iterations", 128, "number of iterations for neural mode")
+	// FlagOptimizer is the optimizer for neural mode
+	FlagOptimizer = flag.String("optimizer", "sgd", "optimizer for neural mode: sgd or adam")
+	// FlagLogEvery is the number of epochs between cost logs
+	FlagLogEvery = flag.Int("log-every", 1, "print the neural mode cost every n epochs, the final epoch is always printed")
+	// FlagProgress shows a progress bar for neural mode
+	FlagProgress = flag.Bool("progress", false, "show a neural mode progress bar on standard error")
+	// FlagInit is the initialization of the first neural layer
+	FlagInit = flag.String("init", "random", "initialization of the first neural layer: random, adjacency to warm-start from the adjacency matrix or identity for the identity scaled by the mean eigenvalue magnitude")
+	// FlagLoss is the loss function for neural mode
+	FlagLoss = flag.String("loss", "quadratic", "loss function for neural mode: quadratic or crossentropy, which needs the targets λx of the eigenpairs in [0, 1]")
+	// FlagMomentum is the sgd momentum
+	FlagMomentum = flag.Float64("momentum", 0, "momentum for the sgd optimizer, 0 disables")
+	// FlagBeta1 is the adam first moment decay rate
+	FlagBeta1 = flag.Float64("beta1", .9, "adam first moment decay rate")
+	// FlagBeta2 is the adam second moment decay rate
+	FlagBeta2 = flag.Float64("beta2", .999, "adam second moment decay rate")
+	// FlagEpsilon is the adam epsilon
+	FlagEpsilon = flag.Float64("epsilon", 1e-8, "adam epsilon")
+	// FlagTol is the cost delta below which neural mode is considered converged
+	FlagTol = flag.Float64("tol", 1e-6, "cost delta below which neural mode is considered converged")
+	// FlagPatience is the number of epochs the cost delta must stay below tol
+	FlagPatience = flag.Int("patience", 10, "number of epochs the cost delta must stay below tol, 0 disables early stopping")
+	// FlagSaveWeights is the file to save the trained neural weights to
+	FlagSaveWeights = flag.String("save-weights", "", "file to save the trained neural weights to")
+	// FlagLoadWeights is the file to load the neural weights from instead of training
+	FlagLoadWeights = flag.String("load-weights", "", "file to load the neural weights from instead of training")
+	// FlagPageRank ranks the nodes with page rank
+	FlagPageRank = flag.Bool("pagerank", false, "rank the nodes with page rank")
+	// FlagTopK limits the rankings to the top nodes
+	FlagTopK = flag.Int("top-k", 0, "print only the top k nodes of each ranking, 0 prints every node")
+	// FlagHITS ranks the nodes by hub and authority score
+	FlagHITS = flag.Bool("hits", false, "rank the nodes by hits hub and authority scores, a node that links to good authorities is a good hub")
+	// FlagKatz ranks the nodes with katz centrality
+	FlagKatz = flag.Bool("katz", false, "rank the nodes with katz centrality")
+	// FlagKatzAlpha is the katz attenuation factor
+	FlagKatzAlpha = flag.Float64("katz-alpha", .1, "katz attenuation factor, must be below 1/|λ| for the dominant eigenvalue λ")
+	// FlagCommunities detects communities with the modularity matrix
+	FlagCommunities = flag.Bool("communities", false, "detect communities by recursively splitting the leading eigenvector of the modularity matrix")
+	// FlagFiedler bisects the graph with the Fiedler vector
+	FlagFiedler = flag.Bool("fiedler", false, "bisect the graph by the signs of the fiedler vector, the eigenvector of the second smallest laplacian eigenvalue")
+	// FlagBipartite ranks the partitions of a bipartite graph with the singular value decomposition
+	FlagBipartite = flag.Bool("bipartite", false, "rank the two partitions of a bipartite graph by the leading singular vectors of the biadjacency matrix instead of the eigendecomposition")
+	// FlagCompare compares the rankings of every ranking method
+	FlagCompare = flag.Bool("compare", false, "compare the spectral, eigenvector, page rank and katz rankings")
+	// FlagCompareOutput is the file the ranking comparison is written to
+	FlagCompareOutput = flag.String("compare-output", "", "file the ranking comparison is written to instead of stdout")
+	// FlagDamping is the page rank damping factor
+	FlagDamping = flag.Float64("damping", .85, "page rank damping factor")
+	// FlagPower computes the dominant eigenvector with power iteration instead of the full eigendecomposition
+	FlagPower = flag.Bool("power", false, "compute the dominant eigenvector with power iteration instead of the full eigendecomposition")
+	// FlagPowerIterations is the maximum number of power iterations
+	FlagPowerIterations = flag.Int("power-iterations", 1000, "maximum number of power iterations")
+	// FlagPowerTol is the power iteration convergence tolerance
+	FlagPowerTol = flag.Float64("power-tol", 1e-9, "power iteration convergence tolerance")
+	// FlagNormalize is the normalization applied to the adjacency matrix
+	FlagNormalize = flag.String("normalize", "none", "normalization applied to the adjacency matrix: none, row, symmetric or laplacian")
+	// FlagLaplacian eigendecomposes the graph Laplacian instead of the adjacency matrix
+	FlagLaplacian = flag.Bool("laplacian", false, "eigendecompose the graph Laplacian instead of the adjacency matrix")
+	// FlagSigned analyzes a graph with negative edges with the signed Laplacian
+	FlagSigned = flag.Bool("signed", false, "analyze a signed graph with negative edges, such as trust and distrust, print its structural balance and eigendecompose the signed Laplacian instead of the adjacency matrix")
+	// FlagSeed is the random seed, -1 seeds from the current time
+	FlagSeed = flag.Int64("seed", 1, "random seed, -1 seeds from the current time")
+	// FlagL2 is the weight of the l2 penalty on the neural weights
+	FlagL2 = flag.Float64("l2", 0, "weight of the l2 penalty on the neural weights")
+	// FlagEigenOutput is the json file for the eigenvalues and eigenvectors
+	FlagEigenOutput = flag.String("eigen-output", "", "json file for the eigenvalues and eigenvectors")
+	// FlagQuiet suppresses the eigendecomposition and projection dumps
+	FlagQuiet = flag.Bool("quiet", false, "suppress the eigendecomposition and projection dumps")
+	// FlagVerbose is the log level
+	FlagVerbose = flag.Int("v", LevelInfo, "log level: 0 errors, 1 warnings, 2 info such as the rankings, 3 debug such as the eigendecomposition dumps")
+	// FlagCostPlot is the file for the neural mode cost plot
+	FlagCostPlot = flag.String("cost-plot", "cost.png", "file for the neural mode cost plot, empty disables")
+	// FlagLogCost uses a log scale for the cost plot
+	FlagLogCost = flag.Bool("log-cost", false, "use a log scale for the y axis of the cost plot")
+	// FlagCostTitle is the title of the cost plot
+	FlagCostTitle = flag.String("cost-title", "epochs vs cost", "title of the cost plot, the default notes a log scale")
+	// FlagCostX is the x axis label of the cost plot
+	FlagCostX = flag.String("cost-xlabel", "epochs", "x axis label of the cost plot")
+	// FlagCostY is the y axis label of the cost plot
+	FlagCostY = flag.String("cost-ylabel", "cost", "y axis label of the cost plot")
+	// FlagGradientOutput is the csv file for the final gradient of the first neural layer
+	FlagGradientOutput = flag.String("gradient-output", "", "csv file for the gradient of the first neural layer after training, in the format of -learned-output")
+	// FlagAnimate is the file name prefix of the heat maps of the first neural layer
+	FlagAnimate = flag.String("animate", "", "file name prefix of a numbered sequence of png heat maps of the weight magnitudes of the first neural layer during training, such as frame for frame00000.png, empty disables")
+	// FlagAnimateEvery is the number of epochs between heat maps
+	FlagAnimateEvery = flag.Int("animate-every", 10, "number of epochs between -animate heat maps, the final epoch is always saved")
+	// FlagHeatMap is the file for the heat maps of the adjacency and learned matrices
+	FlagHeatMap = flag.String("heatmap", "", "file for the heat maps of the adjacency matrix magnitudes and in neural mode the learned matrix magnitudes, such as heatmap.png for heatmap-adjacency.png and heatmap-learned.png, empty disables")
+	// FlagPalette is the color palette of the heat maps
+	FlagPalette = flag.String("palette", "heat", "color palette of the heat maps: heat, rainbow, blackbody, kindlmann or bluered")
+	// FlagLearnedOutput is the csv file the learned neural weights are written to
+	FlagLearnedOutput = flag.String("learned-output", "", "csv file the learned neural weights are written to with their real and imaginary parts and magnitudes")
+	// FlagCostData is the file for the neural mode cost history
+	FlagCostData = flag.String("cost-data", "", "file for the neural mode cost history")
+	// FlagVectorsPlot is the file for the eigenvector projection plot
+	FlagVectorsPlot = flag.String("vectors-plot", "results.png", "file for the eigenvector projection plot, empty disables")
+	// FlagPhasePlot is the file for the plot of the dominant eigenvector on the complex plane
+	FlagPhasePlot = flag.String("phase-plot", "", "file for the plot of the dominant eigenvector entries on the complex plane, which shows the rotation of directed graphs")
+	// FlagVectorsTitle is the title of the eigenvector projection plot
+	FlagVectorsTitle = flag.String("vectors-title", "x vs y", "title of the eigenvector projection plot")
+	// FlagVectorsX is the x axis label of the eigenvector projection plot
+	FlagVectorsX = flag.String("vectors-xlabel", "x", "x axis label of the eigenvector projection plot")
+	// FlagVectorsY is the y axis label of the eigenvector projection plot
+	FlagVectorsY = flag.String("vectors-ylabel", "y", "y axis label of the eigenvector projection plot")
+	// FlagVectorsData is the file for the eigenvector projection data
+	FlagVectorsData = flag.String("vectors-data", "results.dat", "file for the eigenvector projection data, empty disables")
+	// FlagVectorsJSON is the json file for the eigenvector projection with the clusters and rank scores
+	FlagVectorsJSON = flag.String("vectors-json", "", "json file for the eigenvector projection with the clusters and rank scores")
+	// FlagPlotWidth is the width of the plots in inches
+	FlagPlotWidth = flag.Float64("plot-width", 8, "width of the plots in inches")
+	// FlagPlotHeight is the height of the plots in inches
+	FlagPlotHeight = flag.Float64("plot-height", 8, "height of the plots in inches")
+	// FlagLabels is a file containing a node name per line
+	FlagLabels = flag.String("labels", "", "file containing a node name per line")
+	// FlagComponents is the number of principal components to project onto
+	FlagComponents = flag.Int("components", 2, "number of principal components to project onto, the plot shows the first two")
+	// FlagEigenSide is which eigenvectors are computed
+	FlagEigenSide = flag.String("eigen-side", "right", "eigenvectors to compute: right, left which are used in place of the right ones, or both which also prints the left ones")
+	// FlagSimilarity is the csv file for the node similarity matrix
+	FlagSimilarity = flag.String("similarity", "", "csv file for the cosine similarity of every pair of nodes in the projection onto the principal components, with -heatmap its heat map is saved to heatmap-similarity.png, empty disables")
+	// FlagPCAMode is how complex eigenvectors are reduced to real features for the projection
+	FlagPCAMode = flag.String("pca-mode", "real", "how complex eigenvectors are reduced for the projection: real, abs or realimag, real loses the imaginary parts of directed graphs and abs loses the phases, which collapses a directed cycle to one point")
+	// FlagPCAMatrix is the matrix the principal components are computed from
+	FlagPCAMatrix = flag.String("pca-matrix", "covariance", "matrix the principal components are computed from: covariance, or correlation to standardize the features first when their scales differ")
+	// FlagPCAWeights is a file containing a pca weight per node
+	FlagPCAWeights = flag.String("pca-weights", "", "file containing a non-negative weight per line for each node in the principal component analysis, empty weighs every node equally")
+	// FlagHeatT is the diffusion time of the heat kernel embedding
+	FlagHeatT = flag.Float64("heat-t", 0, "project the heat kernel exp(-t L) of the Laplacian instead of the eigenvectors, t is the diffusion scale: small t keeps the local structure and larger t smooths the embedding towards the communities, around 1/algebraic connectivity, 0 disables")
+	// FlagClusters is the number of k-means clusters for the projection
+	FlagClusters = flag.Int("clusters", 0, "number of k-means clusters for the projection, 0 disables")
 )
 
-// Neural mode
-func Neural(vectors *mat.CDense, values []complex128) {
+// NeuralOptions are the options for neural mode, the reconstruction error of a single layer
+// is reported against Adjacency when it isn't nil
+type NeuralOptions struct {
+	Eta         float64
+	Iterations  int
+	Optimizer   string
+	Beta1       float64
+	Beta2       float64
+	Epsilon     float64
+	Tol         float64
+	Patience    int
+	SaveWeights string
+	LoadWeights string
+	L2          float64
+	Layers      int
+	Clip        float64
+	Batch       int
+	Momentum    float64
+	Loss        string
+	LogEvery    int
+	ProgressBar bool
+	CostPlot    PlotOptions
+	CostData    string
+	Learned     string
+	Adjacency   *mat.Dense
+	ValSplit    float64
+	Restarts    int
+	Gradient    string
+	// Animate is the file name prefix of the heat maps saved during training, empty disables
+	Animate      string
+	AnimateEvery int
+	// HeatMap is the file for the heat map of the learned matrix, empty disables
+	HeatMap string
+	// Init is the initialization of the first layer, one of Inits
+	Init string
+}
+
+// Inits are the initializations of the first neural layer
+var Inits = []string{"random", "adjacency", "identity"}
+
+// Init returns the initial weights of the first neural layer by row, or nil to keep the random
+// weights. adjacency copies the adjacency matrix and identity is the identity matrix scaled by
+// the mean magnitude of the eigenvalues.
+func Init(name string, size int, adjacency *mat.Dense, values []complex128) []float64 {
+	weights := make([]float64, size*size)
+	switch name {
+	case "adjacency":
+		for i := 0; i < size; i++ {
+			for j := 0; j < size; j++ {
+				weights[i*size+j] = adjacency.At(i, j)
+			}
+		}
+	case "identity":
+		scale := 0.0
+		for _, value := range values {
+			scale += cmplx.Abs(value)
+		}
+		scale /= float64(len(values))
+		for i := 0; i < size; i++ {
+			weights[i*size+i] = scale
+		}
+	default:
+		return nil
+	}
+	return weights
+}
+
+// Losses are the loss functions supported by neural mode
+var Losses = []string{"quadratic", "crossentropy"}
+
+// Loss is the named loss between the outputs of the network and the targets, summed over the eigenpairs
+func Loss(name string, outputs, targets tc128.Meta) tc128.Meta {
+	switch name {
+	case "crossentropy":
+		return tc128.Sum(tc128.CrossEntropy(outputs, targets))
+	}
+	return tc128.Sum(tc128.Quadratic(targets, outputs))
+}
+
+// SquaredMagnitude multiplies every entry of a by its conjugate, |a|² = a·ā. The gradient is 2a,
+// the direction of steepest ascent of |a|² in the complex plane, where the holomorphic derivative
+// 2ā of a·a would rotate the weights instead of shrinking them.
+var SquaredMagnitude = tc128.U(func(k tc128.Continuation, a *tc128.V) bool {
+	c := tc128.NewV(a.S...)
+	for _, ax := range a.X {
+		c.X = append(c.X, complex(real(ax)*real(ax)+imag(ax)*imag(ax), 0))
+	}
+	if k(&c) {
+		return true
+	}
+	for i, cd := range c.D {
+		a.D[i] += 2 * cd * a.X[i]
+	}
+	return false
+})
+
+// Eigenpairs returns the inputs and the targets of neural mode for the eigenvector columns: the
+// real parts of x_k and of λ_k x_k followed by their imaginary parts for each column k. A is real,
+// so A x_k = λ_k x_k splits into equations for the real and imaginary parts, which keeps the
+// inputs real and the gradient descent stable, and keeping the imaginary parts keeps degenerate
+// eigenvalues that come back as a complex conjugate pair full rank.
+func Eigenpairs(vectors *mat.CDense, values []complex128, columns []int) (inputs, targets []float64) {
+	size, _ := vectors.Dims()
+	inputs, targets = make([]float64, 0, 2*size*len(columns)), make([]float64, 0, 2*size*len(columns))
+	for _, k := range columns {
+		for i := 0; i < size; i++ {
+			inputs = append(inputs, real(vectors.At(i, k)))
+			targets = append(targets, real(values[k]*vectors.At(i, k)))
+		}
+		for i := 0; i < size; i++ {
+			inputs = append(inputs, imag(vectors.At(i, k)))
+			targets = append(targets, imag(values[k]*vectors.At(i, k)))
+		}
+	}
+	return inputs, targets
+}
+
+// CheckLoss returns an error if the named loss can't be used with the eigenpairs, cross entropy
+// needs every target, the real and imaginary parts of λ_k x_k, to lie in [0, 1]
+func CheckLoss(name string, vectors *mat.CDense, values []complex128) error {
+	if name != "crossentropy" {
+		return nil
+	}
+	size, _ := vectors.Dims()
+	for k := range values {
+		_, targets := Eigenpairs(vectors, values, []int{k})
+		for i, target := range targets {
+			if target < -spectral.Tolerance || target > 1+spectral.Tolerance {
+				return fmt.Errorf("the cross entropy loss needs targets in [0, 1] but eigenpair %d has the target %g for node %d, use -loss quadratic", k, target, i%size)
+			}
+		}
+	}
+	return nil
+}
+
+// TopK returns the first k nodes of the ranking, or all of them when k isn't positive
+func TopK(ranking []int, k int) []int {
+	if k <= 0 || k > len(ranking) {
+		return ranking
+	}
+	return ranking[:k]
+}
+
+// SparseDensity is the density below which edge lists are analyzed as sparse matrices
+const SparseDensity = .1
+
+// SparsePower prints the connected components, the dominant eigenvalue and the power iteration
+// ranking of a sparse graph, returning the error of spectral.PowerIterationCSR when ctx is done
+func SparsePower(ctx context.Context, sparse *spectral.CSR) error {
+	components, _ := spectral.ConnectedComponentsCSR(sparse)
+	Log.Infoln("components", components)
+	Log.Infof("\n")
+	if components > 1 {
+		Log.Warnf("the graph has %d connected components, the dominant eigenvector may concentrate on one of them", components)
+	}
+
+	value, vector, convergence, err := spectral.PowerIterationCSR(ctx, sparse, *FlagPowerIterations, *FlagPowerTol)
+	if err != nil {
+		return err
+	}
+	Log.Infoln(value)
+	PrintConvergence(convergence)
+	Log.Infof("\n")
+	scores := make([]float64, len(vector))
+	for i, v := range vector {
+		scores[i] = math.Abs(v)
+	}
+	for i, node := range TopK(spectral.RankScores(scores), *FlagTopK) {
+		Log.Infoln(i, node, scores[node])
+	}
+	return nil
+}
+
+// PrintConvergence prints the power iteration diagnostics unless -quiet is set and warns when
+// the iterations ran out before converging
+func PrintConvergence(convergence spectral.Convergence) {
+	if !*FlagQuiet {
+		Log.Infoln("iterations", convergence.Iterations, "residual", convergence.Residual, "converged", convergence.Converged)
+	}
+	if !convergence.Converged {
+		Log.Warnf("power iteration didn't converge to -power-tol %g in %d iterations, a large residual means the eigenvector is inaccurate, which happens when the two largest eigenvalue magnitudes are close", *FlagPowerTol, convergence.Iterations)
+	}
+}
+
+// LayerName is the name of the weights of layer l, the first layer is A
+func LayerName(l int) string {
+	if l == 0 {
+		return "A"
+	}
+	return fmt.Sprintf("A%d", l)
+}
+
+// Neural mode learns a matrix A such that A x_k = λ_k x_k for every eigenpair, with more than one
+// layer the layers are joined by a tanh activation. When ctx is done training stops, the outputs
+// are written with the partially trained weights and an error wrapping spectral.ErrCanceled is
+// returned.
+func Neural(ctx context.Context, rng *rand.Rand, size int, vectors *mat.CDense, values []complex128, options NeuralOptions) error {
+	if err := CheckLoss(options.Loss, vectors, values); err != nil {
+		return err
+	}
+	network, err := NewComplexNetwork(rng, size, vectors, values, options)
+	if err != nil {
+		return err
+	}
+	return RunNetwork(ctx, network, size, options)
+}
+
+// Network is the cost graph of neural mode for a type of weights, the training loop and the
+// outputs of RunNetwork are shared by every type
+type Network interface {
+	// Initialize sets the weights to new random values and the first layer to the -init weights
+	Initialize()
+	// Cost evaluates the cost over every training eigenvector without the backward pass
+	Cost() float64
+	// Reset clears the optimizer state before training
+	Reset()
+	// Gradient draws a minibatch if batches are enabled and computes the gradient, returning the
+	// magnitudes of the cost, of its data and regularization parts and of the gradient
+	Gradient() (cost, data, regularization, norm float64)
+	// Update applies the gradient scaled by scaling with the optimizer at epoch i
+	Update(i int, scaling float64)
+	// Validation returns a function computing the cost on the held out eigenvectors, or nil when
+	// none are held out
+	Validation() func() float64
+	// Keep records the current weights as the best and Restore sets the weights to them
+	Keep()
+	Restore()
+	Save(name string, cost float64, epochs int) error
+	Load(name string) error
+	// Weights returns the names and the weights of the layers as complex numbers
+	Weights() ([]string, [][]complex128)
+	// FullGradient returns the gradient of the first layer over every training eigenvector
+	// without applying it
+	FullGradient() []complex128
+}
+
+// ComplexNetwork is the cost graph of neural mode with complex weights
+type ComplexNetwork struct {
+	set, best  tc128.Set
+	cost, full tc128.Meta
+	validate   func() float64
+	sample     func()
+	initialize func()
+	options    NeuralOptions
+	// adam first and second moment estimates, the real and imaginary parts are tracked
+	// separately, and sgd velocities for momentum
+	m, v, velocity [][]complex128
+}
+
+// NewComplexNetwork builds the cost graph of neural mode with complex weights for the eigenpairs
+func NewComplexNetwork(rng *rand.Rand, size int, vectors *mat.CDense, values []complex128, options NeuralOptions) (*ComplexNetwork, error) {
 	random128 := func(a, b float64) complex128 {
-		return complex((b-a)*rand.Float64()+a, (b-a)*rand.Float64()+a)
+		return complex((b-a)*rng.Float64()+a, (b-a)*rng.Float64()+a)
 	}
 
-	set := tc128.NewSet()
-	set.Add("A", Size, Size)
-	set.Add("X", Size, 1)
-	set.Add("Y", Size, 1)
+	n := &ComplexNetwork{set: tc128.NewSet(), options: options}
+	set := &n.set
+	for l := 0; l < options.Layers; l++ {
+		set.Add(LayerName(l), size, size)
+	}
+	train, held, err := Split(rng, size, options.ValSplit)
+	if err != nil {
+		return nil, err
+	}
+	// the eigenpairs are inputs, so they are kept out of the trained set
+	inputs := tc128.NewSet()
+	inputs.Add("X", size, 2*len(train))
+	inputs.Add("Y", size, 2*len(train))
+	inputs.Add("VX", size, 2*len(held))
+	inputs.Add("VY", size, 2*len(held))
 
-	w := set.Weights[0]
-	for i := 0; i < cap(w.X); i++ {
-		w.X = append(w.X, random128(-1, 1))
+	warm := Init(options.Init, size, options.Adjacency, values)
+	n.initialize = func() {
+		for _, w := range set.Weights {
+			w.X = w.X[:0]
+			for i := 0; i < cap(w.X); i++ {
+				w.X = append(w.X, random128(-1, 1))
+			}
+		}
+		for i, value := range warm {
+			set.Weights[0].X[i] = complex(value, 0)
+		}
 	}
+	n.initialize()
 
-	w = set.Weights[1]
-	for i := 0; i < Size; i++ {
-		w.X = append(w.X, vectors.At(0, i))
+	pairs := func(x, y *tc128.V, columns []int) {
+		inputs, targets := Eigenpairs(vectors, values, columns)
+		for i := range inputs {
+			x.X = append(x.X, complex(inputs[i], 0))
+			y.X = append(y.X, complex(targets[i], 0))
+		}
+	}
+	x, y := inputs.Weights[0], inputs.Weights[1]
+	pairs(x, y, train)
+	pairs(inputs.Weights[2], inputs.Weights[3], held)
+
+	input, output := inputs.Get("X"), inputs.Get("Y")
+	if options.Batch > 0 && options.Batch < len(train) {
+		batch := tc128.NewSet()
+		batch.Add("X", size, 2*options.Batch)
+		batch.Add("Y", size, 2*options.Batch)
+		bx, by := batch.Weights[0], batch.Weights[1]
+		bx.X, by.X = bx.X[:cap(bx.X)], by.X[:cap(by.X)]
+		columns := Columns(rng, len(train), options.Batch)
+		n.sample = func() {
+			for b, k := range columns() {
+				copy(bx.X[2*b*size:2*(b+1)*size], x.X[2*k*size:2*(k+1)*size])
+				copy(by.X[2*b*size:2*(b+1)*size], y.X[2*k*size:2*(k+1)*size])
+			}
+		}
+		input, output = batch.Get("X"), batch.Get("Y")
 	}
 
-	w = set.Weights[2]
-	for i := 0; i < Size; i++ {
-		w.X = append(w.X, values[0]*vectors.At(0, i))
+	network := func(input tc128.Meta) tc128.Meta {
+		l1 := tc128.Mul(set.Get("A"), input)
+		for l := 1; l < options.Layers; l++ {
+			l1 = tc128.Mul(set.Get(LayerName(l)), tc128.TanH(l1))
+		}
+		return l1
+	}
+	// the penalty weight is kept out of the trained set so it doesn't contribute to the gradient norm
+	constants := tc128.NewSet()
+	constants.Add("L2", 1, 1)
+	constants.Weights[0].X = append(constants.Weights[0].X, complex(options.L2, 0))
+	regularize := func(cost tc128.Meta) tc128.Meta {
+		if options.L2 > 0 {
+			for _, w := range set.Weights {
+				a := set.Get(w.N)
+				cost = tc128.Add(cost, tc128.Hadamard(constants.Get("L2"), tc128.Sum(SquaredMagnitude(a))))
+			}
+		}
+		return cost
+	}
+	n.cost = regularize(Loss(options.Loss, network(input), output))
+	// the gradient snapshot is over every training eigenvector even when training on minibatches
+	n.full = n.cost
+	if n.sample != nil {
+		n.full = regularize(Loss(options.Loss, network(inputs.Get("X")), inputs.Get("Y")))
+	}
+	if len(held) > 0 {
+		validation := Loss(options.Loss, network(inputs.Get("VX")), inputs.Get("VY"))
+		n.validate = func() float64 {
+			value := 0.0
+			// returning true from the continuation evaluates the cost without the backward pass
+			validation(func(a *tc128.V) bool {
+				value = cmplx.Abs(a.X[0])
+				return true
+			})
+			return value
+		}
 	}
+	return n, nil
+}
 
-	l1 := tc128.Mul(set.Get("A"), set.Get("X"))
-	cost := tc128.Quadratic(set.Get("Y"), l1)
+// Initialize sets the weights to new random values
+func (n *ComplexNetwork) Initialize() {
+	n.initialize()
+}
 
-	eta, iterations := complex128(.3), 128
-	points := make(plotter.XYs, 0, iterations)
+// Cost evaluates the cost over every training eigenvector
+func (n *ComplexNetwork) Cost() float64 {
+	cost := 0.0
+	n.full(func(a *tc128.V) bool {
+		cost = cmplx.Abs(a.X[0])
+		return true
+	})
+	return cost
+}
+
+// Reset clears the optimizer state
+func (n *ComplexNetwork) Reset() {
+	n.m, n.v = make([][]complex128, len(n.set.Weights)), make([][]complex128, len(n.set.Weights))
+	n.velocity = make([][]complex128, len(n.set.Weights))
+	for l, w := range n.set.Weights {
+		n.m[l], n.v[l] = make([]complex128, len(w.X)), make([]complex128, len(w.X))
+		n.velocity[l] = make([]complex128, len(w.X))
+	}
+}
+
+// Gradient computes the gradient of the cost
+func (n *ComplexNetwork) Gradient() (cost, data, regularization, norm float64) {
+	if n.sample != nil {
+		n.sample()
+	}
+	n.set.Zero()
+
+	total := tc128.Gradient(n.cost).X[0]
+	penalty := complex128(0)
+	for _, w := range n.set.Weights {
+		for _, a := range w.X {
+			penalty += complex(real(a)*real(a)+imag(a)*imag(a), 0)
+		}
+	}
+	penalty *= complex(n.options.L2, 0)
+	sum := 0.0
+	for _, p := range n.set.Weights {
+		for _, d := range p.D {
+			sum += cmplx.Abs(d) * cmplx.Abs(d)
+		}
+	}
+	return cmplx.Abs(total), cmplx.Abs(total - penalty), cmplx.Abs(penalty), math.Sqrt(sum)
+}
+
+// Update applies the gradient with the optimizer
+func (n *ComplexNetwork) Update(i int, scaling float64) {
+	eta, beta1, beta2, epsilon := n.options.Eta, n.options.Beta1, n.options.Beta2, n.options.Epsilon
+	for l, w := range n.set.Weights {
+		switch n.options.Optimizer {
+		case "adam":
+			b1, b2 := 1-math.Pow(beta1, float64(i+1)), 1-math.Pow(beta2, float64(i+1))
+			m, v := n.m[l], n.v[l]
+			for j, d := range w.D {
+				g := d * complex(scaling, 0)
+				m[j] = complex(beta1, 0)*m[j] + complex(1-beta1, 0)*g
+				v[j] = complex(beta2*real(v[j])+(1-beta2)*real(g)*real(g),
+					beta2*imag(v[j])+(1-beta2)*imag(g)*imag(g))
+				mr, mi := real(m[j])/b1, imag(m[j])/b1
+				vr, vi := real(v[j])/b2, imag(v[j])/b2
+				w.X[j] -= complex(eta*mr/(math.Sqrt(vr)+epsilon), eta*mi/(math.Sqrt(vi)+epsilon))
+			}
+		default:
+			velocity := n.velocity[l]
+			for j, d := range w.D {
+				velocity[j] = complex(n.options.Momentum, 0)*velocity[j] - complex(eta, 0)*d*complex(scaling, 0)
+				w.X[j] += velocity[j]
+			}
+		}
+	}
+}
+
+// Validation returns the cost on the held out eigenvectors
+func (n *ComplexNetwork) Validation() func() float64 {
+	return n.validate
+}
+
+// Keep records the current weights as the best
+func (n *ComplexNetwork) Keep() {
+	n.best = n.set.Copy()
+}
+
+// Restore sets the weights to the best weights
+func (n *ComplexNetwork) Restore() {
+	for i, w := range n.best.Weights {
+		copy(n.set.Weights[i].X, w.X)
+	}
+}
+
+// Save saves the weights
+func (n *ComplexNetwork) Save(name string, cost float64, epochs int) error {
+	return n.set.Save(name, complex(cost, 0), epochs)
+}
+
+// Load loads the weights
+func (n *ComplexNetwork) Load(name string) error {
+	return LoadWeights(name, &n.set)
+}
+
+// Weights returns the names and the weights of the layers
+func (n *ComplexNetwork) Weights() ([]string, [][]complex128) {
+	layers, weights := make([]string, 0, len(n.set.Weights)), make([][]complex128, 0, len(n.set.Weights))
+	for _, w := range n.set.Weights {
+		layers, weights = append(layers, w.N), append(weights, w.X)
+	}
+	return layers, weights
+}
+
+// FullGradient returns the gradient of the first layer over every training eigenvector
+func (n *ComplexNetwork) FullGradient() []complex128 {
+	n.set.Zero()
+	tc128.Gradient(n.full)
+	return n.set.Weights[0].D
+}
+
+// RunNetwork trains the network with restarts or loads its weights, then writes its outputs.
+// Every restart trains from new random weights and the weights with the lowest final cost are
+// kept. When ctx is done the outputs are written with the partially trained weights and the
+// error of Canceled is returned.
+func RunNetwork(ctx context.Context, network Network, size int, options NeuralOptions) error {
+	// canceled is the error of a canceled training, which is returned after writing the outputs
+	var canceled error
+	if options.LoadWeights != "" {
+		err := network.Load(options.LoadWeights)
+		if err != nil {
+			return err
+		}
+	} else {
+		var (
+			total           float64
+			epochs, restart int
+			progress        *Progress
+		)
+		Log.Infoln("init", options.Init, "cost", network.Cost())
+		for r := 0; r < options.Restarts || r == 0; r++ {
+			if r > 0 {
+				network.Initialize()
+			}
+			final, e, p, err := Train(ctx, network, options)
+			if err != nil && r > 0 {
+				// a canceled restart is dropped for the best finished one
+				canceled = err
+				break
+			}
+			if options.Restarts > 1 {
+				Log.Infoln("restart", r, "cost", final)
+			}
+			if r == 0 || final < total {
+				network.Keep()
+				total, epochs, restart, progress = final, e, r, p
+			}
+			if err != nil {
+				canceled = err
+				break
+			}
+		}
+		if options.Restarts > 1 {
+			network.Restore()
+			Log.Infoln("best restart", restart, "cost", total)
+		}
+		if err := progress.Finish(); err != nil {
+			return err
+		}
+		if options.SaveWeights != "" {
+			err := network.Save(options.SaveWeights, total, epochs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	layers, weights := network.Weights()
+	for l, w := range weights {
+		if l > 0 {
+			Log.Debugf("\n")
+		}
+		for i := 0; i < size; i++ {
+			for j := 0; j < size; j++ {
+				Log.Debugf("%f ", cmplx.Abs(w[i*size+j]))
+			}
+			Log.Debugf("\n")
+		}
+	}
+
+	if options.Adjacency != nil && options.Layers == 1 {
+		Log.Infof("\n")
+		Log.Infoln("reconstruction error", Reconstruction(options.Adjacency, weights[0]))
+	}
+
+	if err := WeightHeatMap(options, options.HeatMap, "learned", Magnitudes(weights[0])); err != nil {
+		return err
+	}
+
+	if options.Gradient != "" {
+		// the gradient is computed without applying it, so the trained weights are unchanged
+		gradient := network.FullGradient()
+		if err := WriteLearned(options.Gradient, size, layers[:1], [][]complex128{gradient}); err != nil {
+			return err
+		}
+	}
+
+	if options.Learned != "" {
+		if err := WriteLearned(options.Learned, size, layers, weights); err != nil {
+			return err
+		}
+	}
+	return canceled
+}
+
+// Progress tracks the cost during training, detecting convergence and writing the cost history
+type Progress struct {
+	Options NeuralOptions
+	Points  plotter.XYs
+	Norms   []float64
+	// Validate returns the cost on the held out eigenvectors if it isn't nil
+	Validate   func() float64
+	Validation plotter.XYs
+	// Magnitudes returns the weight magnitudes of the first layer for the heat maps of Animate
+	Magnitudes func() []float64
+	previous   float64
+	stalled    int
+	percent    int
+	err        error
+}
+
+// Step records the cost and the gradient norm of epoch i, data and regularization are the parts
+// of the cost when l2 regularization is enabled, returning true once the cost has converged.
+// The cost is printed every LogEvery epochs and for the final epoch, followed by the validation
+// cost when there are held out eigenvectors. A heat map is saved every AnimateEvery epochs and
+// for the final epoch when Animate is set.
+func (p *Progress) Step(i int, cost, data, regularization, norm float64) bool {
+	p.Points = append(p.Points, plotter.XY{X: float64(i), Y: cost})
+	p.Norms = append(p.Norms, norm)
+	validation := 0.0
+	if p.Validate != nil {
+		validation = p.Validate()
+		p.Validation = append(p.Validation, plotter.XY{X: float64(i), Y: validation})
+	}
+	if i > 0 && math.Abs(cost-p.previous) < p.Options.Tol {
+		p.stalled++
+	} else {
+		p.stalled = 0
+	}
+	p.previous = cost
+	converged := p.Options.Patience > 0 && p.stalled >= p.Options.Patience
+	last := converged || i == p.Options.Iterations-1
+
+	every := p.Options.LogEvery
+	if every < 1 {
+		every = 1
+	}
+	if i%every == 0 || last {
+		line := []interface{}{i, cost, norm}
+		if p.Options.L2 > 0 {
+			line = []interface{}{i, data, regularization, norm}
+		}
+		if p.Validate != nil {
+			line = append(line, validation)
+		}
+		Log.Infoln(line...)
+	}
+	if p.Options.Animate != "" && p.Magnitudes != nil && p.err == nil {
+		every := p.Options.AnimateEvery
+		if every < 1 {
+			every = 1
+		}
+		if i%every == 0 || last {
+			p.err = p.Frame(i)
+		}
+	}
+	if p.Options.ProgressBar {
+		const width = 40
+		percent := 100
+		if !last {
+			percent = 100 * (i + 1) / p.Options.Iterations
+		}
+		if i == 0 || percent != p.percent {
+			bar := strings.Repeat("=", width*percent/100) + strings.Repeat(" ", width-width*percent/100)
+			fmt.Fprintf(os.Stderr, "\r[%s] %3d%%", bar, percent)
+		}
+		p.percent = percent
+		if last {
+			fmt.Fprintf(os.Stderr, "\n")
+		}
+	}
+	if converged {
+		Log.Infoln("converged at epoch", i)
+	}
+	return converged
+}
+
+// Frame saves the heat map of the first layer at epoch i
+func (p *Progress) Frame(i int) error {
+	name := fmt.Sprintf("%s%05d.png", p.Options.Animate, i)
+	return WeightHeatMap(p.Options, name, fmt.Sprintf("epoch %d", i), p.Magnitudes())
+}
+
+// Magnitudes returns the magnitudes of the weights
+func Magnitudes(weights []complex128) []float64 {
+	magnitudes := make([]float64, len(weights))
+	for i, w := range weights {
+		magnitudes[i] = cmplx.Abs(w)
+	}
+	return magnitudes
+}
+
+// WeightHeatMap saves a heat map of the square matrix of weight magnitudes with the size of the
+// cost plot, the colors are scaled to the largest magnitude of the adjacency matrix so that the
+// heat maps of the weights and of the adjacency matrix are comparable
+func WeightHeatMap(options NeuralOptions, name, title string, magnitudes []float64) error {
+	plot := options.CostPlot
+	plot.Name, plot.Title, plot.X, plot.Y = name, title, "column", "row"
+	size := int(math.Round(math.Sqrt(float64(len(magnitudes)))))
+	return HeatMap(plot, Grid{Size: size, Values: magnitudes}, 0, Scale(options.Adjacency, magnitudes))
+}
+
+// Scale returns the largest magnitude of the adjacency matrix, or of the values if the matrix is
+// nil or zero
+func Scale(adjacency *mat.Dense, values []float64) float64 {
+	max := 0.0
+	if adjacency != nil {
+		rows, cols := adjacency.Dims()
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				max = math.Max(max, math.Abs(adjacency.At(r, c)))
+			}
+		}
+	}
+	if max == 0 {
+		for _, value := range values {
+			max = math.Max(max, value)
+		}
+	}
+	return max
+}
+
+// Finish writes the cost plot and the cost data, returning the first error saving a heat map
+func (p *Progress) Finish() error {
+	if p.err != nil {
+		return p.err
+	}
+	options := p.Options.CostPlot
+	if p.Validate != nil {
+		options.Legend = "training"
+		options.Series = []Series{{Name: "validation", Points: p.Validation}}
+	}
+	err := Scatter(options, 1, p.Points)
+	if err != nil {
+		return err
+	}
+
+	if p.Options.CostData != "" {
+		output, err := os.Create(p.Options.CostData)
+		if err != nil {
+			return err
+		}
+		defer output.Close()
+		for i, point := range p.Points {
+			if p.Validate != nil {
+				fmt.Fprintf(output, "%d %f %f %f\n", int(point.X), point.Y, p.Norms[i], p.Validation[i].Y)
+				continue
+			}
+			fmt.Fprintf(output, "%d %f %f\n", int(point.X), point.Y, p.Norms[i])
+		}
+	}
+	return nil
+}
+
+// Split holds out a random fraction of the eigenvector columns for validation, returning the
+// training and the held out columns in order. At least one column is held out when the fraction
+// is positive and at least one is kept for training, so a split needs more than one eigenvector.
+func Split(rng *rand.Rand, size int, fraction float64) (train, held []int, err error) {
+	if fraction <= 0 {
+		train = make([]int, size)
+		for i := range train {
+			train[i] = i
+		}
+		return train, nil, nil
+	}
+	count := int(math.Round(fraction * float64(size)))
+	if count < 1 {
+		count = 1
+	}
+	if count >= size {
+		return nil, nil, fmt.Errorf("val-split %g leaves no eigenvectors of %d to train on, it needs more than one eigenvector", fraction, size)
+	}
+	columns := rng.Perm(size)
+	held, train = columns[:count], columns[count:]
+	sort.Ints(held)
+	sort.Ints(train)
+	return train, held, nil
+}
+
+// Columns returns a function that shuffles the eigenvector columns and returns the first batch of them
+func Columns(rng *rand.Rand, size, batch int) func() []int {
+	columns := make([]int, size)
+	for i := range columns {
+		columns[i] = i
+	}
+	return func() []int {
+		rng.Shuffle(len(columns), func(i, j int) {
+			columns[i], columns[j] = columns[j], columns[i]
+		})
+		return columns[:batch]
+	}
+}
+
+// Canceled returns an error wrapping spectral.ErrCanceled if ctx is done after the epochs, ending
+// the line of the progress bar
+func Canceled(ctx context.Context, epochs int, options NeuralOptions) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if options.ProgressBar {
+		fmt.Fprintf(os.Stderr, "\n")
+	}
+	return fmt.Errorf("%w: training stopped after %d epochs: %v", spectral.ErrCanceled, epochs, ctx.Err())
+}
+
+// Train trains every weight of the network, returning the final cost, the number of epochs and
+// the progress to write the cost plot and data with. The cost on the held out eigenvectors is
+// reported after every epoch when there are any. ctx is checked before every epoch and when it
+// is done the partial result is returned with the error of Canceled.
+func Train(ctx context.Context, network Network, options NeuralOptions) (float64, int, *Progress, error) {
+	network.Reset()
+	progress := Progress{Options: options, Points: make(plotter.XYs, 0, options.Iterations), Validate: network.Validation()}
+	progress.Magnitudes = func() []float64 {
+		_, weights := network.Weights()
+		return Magnitudes(weights[0])
+	}
+	last := 0.0
 	i := 0
-	for i < iterations {
-		set.Zero()
+	for i < options.Iterations {
+		if err := Canceled(ctx, i, options); err != nil {
+			return last, i, &progress, err
+		}
+		cost, data, regularization, norm := network.Gradient()
+		last = cost
+		scaling := 1.0
+		if options.Clip > 0 && norm > options.Clip {
+			scaling = options.Clip / norm
+		}
+		network.Update(i, scaling)
 
-		total := tc128.Gradient(cost).X[0]
-		sum := 0.0
-		for _, p := range set.Weights {
-			for _, d := range p.D {
-				sum += cmplx.Abs(d) * cmplx.Abs(d)
+		converged := progress.Step(i, cost, data, regularization, norm)
+		i++
+		if converged {
+			break
+		}
+	}
+
+	return last, i, &progress, nil
+}
+
+// Reconstruction returns the Frobenius norm of the difference between the real part of
+// the learned weights and the adjacency matrix
+func Reconstruction(adjacency *mat.Dense, weights []complex128) float64 {
+	size, _ := adjacency.Dims()
+	sum := 0.0
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			d := real(weights[i*size+j]) - adjacency.At(i, j)
+			sum += d * d
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// LoadWeights loads previously trained weights into every weight in the set
+func LoadWeights(name string, set *tc128.Set) error {
+	loaded := tc128.NewSet()
+	_, _, err := loaded.Open(name)
+	if err != nil {
+		return err
+	}
+	for _, w := range set.Weights {
+		layer := w.N
+		a, ok := loaded.ByName[layer]
+		if !ok {
+			return fmt.Errorf("%s: no %s weights", name, layer)
+		}
+		if len(a.S) != 2 || a.S[0] != w.S[0] || a.S[1] != w.S[1] {
+			return fmt.Errorf("%s: %s weights are %v, expected %dx%d", name, layer, a.S, w.S[0], w.S[1])
+		}
+		w.Set(a.X)
+	}
+	return nil
+}
+
+// ValidatePlot checks that the extension of the plot file name is a format supported by gonum plot
+func ValidatePlot(name string) error {
+	format := strings.ToLower(filepath.Ext(name))
+	if len(format) != 0 {
+		format = format[1:]
+	}
+	formats := draw.Formats()
+	for _, f := range formats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: unsupported plot format %q, supported formats are %s", name, format, strings.Join(formats, ", "))
+}
+
+// PlotOptions are the options for a plot
+type PlotOptions struct {
+	Name   string
+	Title  string
+	X      string
+	Y      string
+	Width  float64
+	Height float64
+	Labels []string
+	Groups []int
+	LogY   bool
+	// Legend names the points in the legend when there are extra series
+	Legend string
+	// Series are extra series drawn in their own color
+	Series []Series
+	// Palette is the color palette of heat maps, one of Palettes
+	Palette string
+}
+
+// Series is a named series of points in a plot
+type Series struct {
+	Name   string
+	Points plotter.XYs
+}
+
+// LogEpsilon is the smallest value plotted on a log scale
+const LogEpsilon = 1e-12
+
+// Scatter saves a scatter plot of the points, the format is determined by the extension
+// and nothing is saved if the name is empty
+func Scatter(options PlotOptions, radius float64, points plotter.XYs) error {
+	if options.Name == "" {
+		return nil
+	}
+	if err := ValidatePlot(options.Name); err != nil {
+		return err
+	}
+
+	p := plot.New()
+
+	p.Title.Text = options.Title
+	p.X.Label.Text = options.X
+	p.Y.Label.Text = options.Y
+	clamp := func(points plotter.XYs) plotter.XYs {
+		if !options.LogY {
+			return points
+		}
+		clamped := make(plotter.XYs, len(points))
+		for i, point := range points {
+			if point.Y < LogEpsilon || math.IsNaN(point.Y) {
+				point.Y = LogEpsilon
 			}
+			clamped[i] = point
 		}
-		norm := float64(math.Sqrt(float64(sum)))
-		scaling := float64(1)
-		if norm > 1 {
-			scaling = 1 / norm
+		return clamped
+	}
+	if options.LogY {
+		p.Y.Scale = plot.LogScale{}
+		p.Y.Tick.Marker = plot.LogTicks{Prec: -1}
+		points = clamp(points)
+	}
+
+	scatter, err := plotter.NewScatter(points)
+	if err != nil {
+		return err
+	}
+	scatter.GlyphStyle.Radius = vg.Length(radius)
+	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
+	if options.Groups != nil {
+		scatter.GlyphStyleFunc = func(i int) draw.GlyphStyle {
+			style := scatter.GlyphStyle
+			style.Color = plotutil.Color(options.Groups[i])
+			return style
+		}
+	}
+	p.Add(scatter)
+	if len(options.Series) > 0 {
+		p.Legend.Add(options.Legend, scatter)
+	}
+	for i, series := range options.Series {
+		s, err := plotter.NewScatter(clamp(series.Points))
+		if err != nil {
+			return err
 		}
+		s.GlyphStyle.Radius = vg.Length(radius)
+		s.GlyphStyle.Shape = draw.CircleGlyph{}
+		s.GlyphStyle.Color = plotutil.Color(i + 1)
+		p.Add(s)
+		p.Legend.Add(series.Name, s)
+	}
 
-		w := set.Weights[0]
-		for l, d := range w.D {
-			w.X[l] -= eta * d * complex(scaling, 0)
+	if options.Labels != nil {
+		labels, err := plotter.NewLabels(plotter.XYLabels{
+			XYs:    points,
+			Labels: options.Labels,
+		})
+		if err != nil {
+			return err
 		}
+		labels.Offset = vg.Point{X: vg.Length(radius), Y: vg.Length(radius)}
+		p.Add(labels)
+	}
+
+	return p.Save(vg.Length(options.Width)*vg.Inch, vg.Length(options.Height)*vg.Inch, options.Name)
+}
+
+// Grid is a square matrix of values stored by row for a heat map, row r is drawn at y = r
+type Grid struct {
+	Size   int
+	Values []float64
+}
+
+// Dims returns the number of columns and rows
+func (g Grid) Dims() (c, r int) {
+	return g.Size, g.Size
+}
+
+// Z returns the value at column c and row r
+func (g Grid) Z(c, r int) float64 {
+	return g.Values[r*g.Size+c]
+}
+
+// X returns the coordinate of column c
+func (g Grid) X(c int) float64 {
+	return float64(c)
+}
+
+// Y returns the coordinate of row r
+func (g Grid) Y(r int) float64 {
+	return float64(r)
+}
+
+// Palettes are the color palettes of the heat maps
+var Palettes = []string{"heat", "rainbow", "blackbody", "kindlmann", "bluered"}
+
+// Palette returns the named color palette, an empty name is heat
+func Palette(name string) (palette.Palette, error) {
+	const colors = 12
+	switch name {
+	case "", "heat":
+		return palette.Heat(colors, 1), nil
+	case "rainbow":
+		return palette.Rainbow(colors, palette.Blue, palette.Red, 1, 1, 1), nil
+	case "blackbody":
+		return moreland.BlackBody().Palette(colors), nil
+	case "kindlmann":
+		return moreland.Kindlmann().Palette(colors), nil
+	case "bluered":
+		return moreland.SmoothBlueRed().Palette(colors), nil
+	}
+	return nil, fmt.Errorf("unknown palette %s, supported palettes are %s", name, strings.Join(Palettes, ", "))
+}
+
+// HeatMapName returns the name of a heat map file, the part is added before the extension
+func HeatMapName(name, part string) string {
+	if name == "" {
+		return ""
+	}
+	extension := filepath.Ext(name)
+	return strings.TrimSuffix(name, extension) + "-" + part + extension
+}
 
-		points = append(points, plotter.XY{X: float64(i), Y: float64(cmplx.Abs(total))})
-		fmt.Println(i, cmplx.Abs(total))
-		i++
+// HeatMap saves a heat map of the grid with the colors scaled from min to max, values outside
+// the range are drawn in the color of the nearest end. The format is determined by the extension
+// and nothing is saved if the name is empty.
+func HeatMap(options PlotOptions, grid Grid, min, max float64) error {
+	if options.Name == "" {
+		return nil
+	}
+	if err := ValidatePlot(options.Name); err != nil {
+		return err
+	}
+	colors, err := Palette(options.Palette)
+	if err != nil {
+		return err
+	}
+	if max <= min {
+		max = min + 1
 	}
 
 	p := plot.New()
 
-	p.Title.Text = "epochs vs cost"
-	p.X.Label.Text = "epochs"
-	p.Y.Label.Text = "cost"
+	p.Title.Text = options.Title
+	p.X.Label.Text = options.X
+	p.Y.Label.Text = options.Y
+	heatmap := plotter.NewHeatMap(grid, colors)
+	heatmap.Min, heatmap.Max = min, max
+	all := colors.Colors()
+	heatmap.Underflow, heatmap.Overflow = all[0], all[len(all)-1]
+	p.Add(heatmap)
 
-	scatter, err := plotter.NewScatter(points)
+	return p.Save(vg.Length(options.Width)*vg.Inch, vg.Length(options.Height)*vg.Inch, options.Name)
+}
+
+// ReductionOptions are the options for the reduction
+type ReductionOptions struct {
+	Components int
+	Plot       PlotOptions
+	Data       string
+	JSON       string
+	Scores     []float64
+	Clusters   int
+	Quiet      bool
+	Timer      *Timer
+	// Similarity is the csv file for the cosine similarity of the projected nodes and
+	// SimilarityHeatMap the file for its heat map
+	Similarity        string
+	SimilarityHeatMap string
+}
+
+// Reduction reduces the matrix and saves the projection to a plot, a data file and a json file
+// with the scores, the nodes are colored by k-means cluster when clusters is greater than zero
+// and only the data files are written when there are more than 3 components
+func Reduction(graph *spectral.Graph, options ReductionOptions) error {
+	size, k, plotOptions := graph.Size(), options.Components, options.Plot
+	proj, err := graph.Project(k)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	scatter.GlyphStyle.Radius = vg.Length(1)
-	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
-	p.Add(scatter)
 
-	err = p.Save(8*vg.Inch, 8*vg.Inch, "cost.png")
+	variances, err := graph.Variances()
 	if err != nil {
-		panic(err)
+		return err
 	}
-
-	for i := 0; i < Size; i++ {
-		for j := 0; j < Size; j++ {
-			value := set.Weights[0].X[i*Size+j]
-			fmt.Printf("%f ", cmplx.Abs(value))
-		}
-		fmt.Printf("\n")
+	matrix, weighting := graph.PCAMatrix, "unweighted"
+	if matrix == "" {
+		matrix = "covariance"
 	}
-}
-
-// Reduction reduces the matrix
-func Reduction(name string, ranks *mat.Dense) {
-	var pc stat.PC
-	ok := pc.PrincipalComponents(ranks, nil)
-	if !ok {
-		panic("PrincipalComponents failed")
+	if graph.PCAWeights != nil {
+		weighting = "weighted"
+	}
+	Log.Infof("\n")
+	Log.Infoln("pca", matrix, weighting)
+	total, cumulative := 0.0, 0.0
+	for _, variance := range variances {
+		total += variance
+	}
+	if !options.Quiet {
+		Log.Infof("\n")
+		for i, variance := range variances {
+			if total > 0 {
+				variance /= total
+			}
+			cumulative += variance
+			Log.Infoln(i, variance, cumulative)
+		}
+		Log.Infof("\n")
 	}
-	k := 2
-	var proj mat.Dense
-	var vec mat.Dense
-	pc.VectorsTo(&vec)
-	proj.Mul(ranks, vec.Slice(0, Size, 0, k))
 
-	fmt.Printf("\n")
 	points := make(plotter.XYs, 0, 8)
-	for i := 0; i < Size; i++ {
-		fmt.Println(proj.At(i, 0), proj.At(i, 1))
-		points = append(points, plotter.XY{X: proj.At(i, 0), Y: proj.At(i, 1)})
+	for i := 0; i < size; i++ {
+		if !options.Quiet {
+			row := make([]interface{}, k)
+			for j := range row {
+				row[j] = proj.At(i, j)
+			}
+			Log.Debugln(row...)
+		}
+		if k > 1 {
+			points = append(points, plotter.XY{X: proj.At(i, 0), Y: proj.At(i, 1)})
+		}
 	}
 
-	p := plot.New()
+	if options.Clusters > 0 {
+		plotOptions.Groups = spectral.KMeans(proj, options.Clusters, 100)
+		Log.Infof("\n")
+		for i, cluster := range plotOptions.Groups {
+			Log.Infoln(i, cluster)
+		}
+	}
+	options.Timer.Mark("pca")
 
-	p.Title.Text = "x vs y"
-	p.X.Label.Text = "x"
-	p.Y.Label.Text = "y"
+	if k == 2 || k == 3 {
+		err = Scatter(plotOptions, 3, points)
+		if err != nil {
+			return err
+		}
+		options.Timer.Mark("plotting")
+	}
 
-	scatter, err := plotter.NewScatter(points)
-	if err != nil {
-		panic(err)
+	if options.Similarity != "" {
+		similarity := spectral.CosineSimilarity(proj)
+		err = WriteSimilarity(options.Similarity, similarity)
+		if err != nil {
+			return err
+		}
+		err = HeatMap(PlotOptions{
+			Name:    options.SimilarityHeatMap,
+			Title:   "cosine similarity",
+			X:       "node",
+			Y:       "node",
+			Width:   plotOptions.Width,
+			Height:  plotOptions.Height,
+			Palette: plotOptions.Palette,
+		}, Grid{Size: size, Values: similarity.RawMatrix().Data}, -1, 1)
+		if err != nil {
+			return err
+		}
+		options.Timer.Mark("similarity")
 	}
-	scatter.GlyphStyle.Radius = vg.Length(3)
-	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
-	p.Add(scatter)
 
-	err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("%s.png", name))
-	if err != nil {
-		panic(err)
+	if options.JSON != "" {
+		err = WriteProjection(options.JSON, proj, plotOptions.Labels, plotOptions.Groups, options.Scores)
+		if err != nil {
+			return err
+		}
 	}
 
-	output, err := os.Create(fmt.Sprintf("%s.dat", name))
+	if options.Data == "" {
+		return nil
+	}
+	output, err := os.Create(options.Data)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer output.Close()
-	for _, point := range points {
-		fmt.Fprintf(output, "%f %f\n", point.X, point.Y)
+	for i := 0; i < size; i++ {
+		for j := 0; j < k; j++ {
+			if j > 0 {
+				fmt.Fprintf(output, " ")
+			}
+			fmt.Fprintf(output, "%f", proj.At(i, j))
+		}
+		fmt.Fprintf(output, "\n")
 	}
+	return nil
 }
 
 // NeuralReduction reduces the matrix using a neural network
-func NeuralReduction(name string, ranks *mat.CDense) {
+func NeuralReduction(rng *rand.Rand, name string, size int, ranks *mat.CDense) error {
 	random128 := func(a, b float64) complex128 {
-		return complex((b-a)*rand.Float64()+a, (b-a)*rand.Float64()+a)
+		return complex((b-a)*rng.Float64()+a, (b-a)*rng.Float64()+a)
 	}
 
 	set := tc128.NewSet()
-	set.Add("A", Size, Size)
+	set.Add("A", size, size)
 	set.Add("N", 1, 1)
 
 	optimize := tc128.NewSet()
-	optimize.Add("X", Size, 2)
+	optimize.Add("X", size, 2)
 
 	w := set.Weights[0]
-	for i := 0; i < Size; i++ {
-		for j := 0; j < Size; j++ {
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
 			w.X = append(w.X, ranks.At(i, j))
 		}
 	}
@@ -225,108 +1493,827 @@ func NeuralReduction(name string, ranks *mat.CDense) {
 		}
 
 		points = append(points, plotter.XY{X: float64(i), Y: float64(cmplx.Abs(total))})
-		fmt.Println(i, cmplx.Abs(total))
+		Log.Infoln(i, cmplx.Abs(total))
 		i++
 	}
 
-	p := plot.New()
+	err := Scatter(PlotOptions{
+		Name:   "cost.png",
+		Title:  "epochs vs cost",
+		X:      "epochs",
+		Y:      "cost",
+		Width:  8,
+		Height: 8,
+	}, 1, points)
+	if err != nil {
+		return err
+	}
 
-	p.Title.Text = "epochs vs cost"
-	p.X.Label.Text = "epochs"
-	p.Y.Label.Text = "cost"
+	points = make(plotter.XYs, 0, 8)
+	reduced := optimize.Weights[0]
+	for i := 0; i < size; i++ {
+		a, b := cmplx.Abs(reduced.X[i]), cmplx.Abs(reduced.X[i+size])
+		Log.Debugln(a, b)
+		points = append(points, plotter.XY{X: a, Y: b})
+	}
 
-	scatter, err := plotter.NewScatter(points)
+	err = Scatter(PlotOptions{
+		Name:   fmt.Sprintf("%s.png", name),
+		Title:  "x vs y",
+		X:      "x",
+		Y:      "y",
+		Width:  8,
+		Height: 8,
+	}, 3, points)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	scatter.GlyphStyle.Radius = vg.Length(1)
-	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
-	p.Add(scatter)
+	return nil
+}
 
-	err = p.Save(8*vg.Inch, 8*vg.Inch, "cost.png")
-	if err != nil {
-		panic(err)
+// OutputFlags are the flags that name the files that are written
+var OutputFlags = []*string{
+	FlagCostPlot, FlagCostData, FlagVectorsPlot, FlagVectorsData, FlagVectorsJSON, FlagEigenOutput,
+	FlagPhasePlot, FlagDOTOutput, FlagCompareOutput, FlagLearnedOutput, FlagGradientOutput, FlagSaveWeights,
+	FlagProfile, FlagAnimate, FlagHeatMap, FlagRankingOutput, FlagSimilarity, FlagManifest,
+}
+
+// PrefixOutputs puts every output file that has a relative name in the directory
+func PrefixOutputs(directory string) {
+	for _, name := range OutputFlags {
+		if *name != "" && !filepath.IsAbs(*name) {
+			*name = filepath.Join(directory, *name)
+		}
+	}
+}
+
+// WriteManifest hashes the input file and writes the manifest to -manifest
+func WriteManifest(manifest *Manifest) error {
+	if format, name := InputSource(); name != "" {
+		if err := manifest.HashInput(format, name); err != nil {
+			return err
+		}
 	}
+	return manifest.Write(*FlagManifest)
+}
 
-	points = make(plotter.XYs, 0, 8)
-	reduced := optimize.Weights[0]
-	for i := 0; i < Size; i++ {
-		a, b := cmplx.Abs(reduced.X[i]), cmplx.Abs(reduced.X[i+Size])
-		fmt.Println(a, b)
-		points = append(points, plotter.XY{X: a, Y: b})
+// ValidateFlags checks the plot formats and the flags that name a choice
+func ValidateFlags() error {
+	for _, name := range []string{*FlagCostPlot, *FlagVectorsPlot, *FlagPhasePlot, *FlagHeatMap} {
+		if name == "" {
+			continue
+		}
+		if err := ValidatePlot(name); err != nil {
+			return err
+		}
 	}
 
-	p = plot.New()
+	if *FlagLayers < 1 {
+		return fmt.Errorf("layers %d must be at least 1", *FlagLayers)
+	}
 
-	p.Title.Text = "x vs y"
-	p.X.Label.Text = "x"
-	p.Y.Label.Text = "y"
+	switch *FlagOptimizer {
+	case "sgd", "adam":
+	default:
+		return fmt.Errorf("unknown optimizer %s", *FlagOptimizer)
+	}
 
-	scatter, err = plotter.NewScatter(points)
-	if err != nil {
-		panic(err)
+	pcaMode := false
+	for _, mode := range spectral.PCAModes {
+		pcaMode = pcaMode || *FlagPCAMode == mode
+	}
+	if !pcaMode {
+		return fmt.Errorf("unknown pca mode %s, supported modes are %s", *FlagPCAMode, strings.Join(spectral.PCAModes, ", "))
+	}
+
+	pcaMatrix := false
+	for _, matrix := range spectral.PCAMatrices {
+		pcaMatrix = pcaMatrix || *FlagPCAMatrix == matrix
+	}
+	if !pcaMatrix {
+		return fmt.Errorf("unknown pca matrix %s, supported matrices are %s", *FlagPCAMatrix, strings.Join(spectral.PCAMatrices, ", "))
+	}
+
+	eigenSide := false
+	for _, side := range spectral.EigenSides {
+		eigenSide = eigenSide || *FlagEigenSide == side
+	}
+	if !eigenSide {
+		return fmt.Errorf("unknown eigen side %s, supported sides are %s", *FlagEigenSide, strings.Join(spectral.EigenSides, ", "))
+	}
+
+	// normalizing a single node checks the mode without duplicating the list of modes
+	if _, err := spectral.Normalize(mat.NewDense(1, 1, nil), *FlagNormalize); err != nil {
+		return err
+	}
+
+	if *FlagSigned && *FlagLaplacian {
+		return errors.New("-signed and -laplacian can't be combined, -signed eigendecomposes the signed laplacian")
+	}
+	if *FlagSigned && *FlagNormalize != "" && *FlagNormalize != "none" {
+		return fmt.Errorf("-signed and -normalize %s can't be combined, the degrees of a signed graph can be negative", *FlagNormalize)
+	}
+
+	if *FlagRestarts < 1 {
+		return fmt.Errorf("restarts %d must be at least 1", *FlagRestarts)
+	}
+
+	if _, err := Palette(*FlagPalette); err != nil {
+		return err
+	}
+
+	initialization := false
+	for _, name := range Inits {
+		initialization = initialization || *FlagInit == name
+	}
+	if !initialization {
+		return fmt.Errorf("unknown init %s, supported inits are %s", *FlagInit, strings.Join(Inits, ", "))
+	}
+
+	if *FlagAnimateEvery < 1 {
+		return fmt.Errorf("animate-every %d must be at least 1", *FlagAnimateEvery)
+	}
+
+	if *FlagHeatT < 0 {
+		return fmt.Errorf("heat-t %g must be at least 0", *FlagHeatT)
+	}
+
+	if *FlagInterval < 0 {
+		return fmt.Errorf("interval %g must be at least 0", *FlagInterval)
+	}
+	if *FlagStreamEvery < 0 {
+		return fmt.Errorf("stream-every %d must be at least 0", *FlagStreamEvery)
 	}
-	scatter.GlyphStyle.Radius = vg.Length(3)
-	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
-	p.Add(scatter)
 
-	err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("%s.png", name))
+	if *FlagValSplit < 0 || *FlagValSplit >= 1 {
+		return fmt.Errorf("val-split %g must be at least 0 and below 1", *FlagValSplit)
+	}
+
+	if *FlagVerbose < LevelError || *FlagVerbose > LevelDebug {
+		return fmt.Errorf("log level %d must be between %d and %d", *FlagVerbose, LevelError, LevelDebug)
+	}
+	return nil
+}
+
+// DumpVectors prints the eigenvector matrix at debug level, the columns are in the order of the
+// sorted eigenvalues and the header row is the eigenvalue of each column
+func DumpVectors(vectors *mat.CDense, values []complex128) {
+	rows, cols := vectors.Dims()
+	for j := 0; j < cols; j++ {
+		Log.Debugf("%f ", values[j])
+	}
+	Log.Debugf("\n")
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			Log.Debugf("%f ", vectors.At(i, j))
+		}
+		Log.Debugf("\n")
+	}
+}
+
+// DumpPolar prints the eigenvector matrix like DumpVectors with each entry and eigenvalue as
+// a (magnitude, phase) pair
+func DumpPolar(vectors *mat.CDense, values []complex128) {
+	rows, cols := vectors.Dims()
+	for j := 0; j < cols; j++ {
+		Log.Debugf("(%f, %f) ", cmplx.Abs(values[j]), cmplx.Phase(values[j]))
+	}
+	Log.Debugf("\n")
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			Log.Debugf("(%f, %f) ", cmplx.Abs(vectors.At(i, j)), cmplx.Phase(vectors.At(i, j)))
+		}
+		Log.Debugf("\n")
+	}
+}
+
+// ComplexInput eigendecomposes the complex matrix in the csv file and ranks the nodes by the
+// magnitude of their entry in the dominant eigenvector, matrices with more than maxSize rows are
+// refused with CheckSize
+func ComplexInput(name string, maxSize int) error {
+	m, err := LoadComplexCSV(name)
+	if err != nil {
+		return err
+	}
+	size, _ := m.Dims()
+	if err := CheckSize(size, maxSize); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	spectrum, err := spectral.DecomposeComplex(m)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	vectors, values := spectrum.Vectors, spectrum.Values
+	ratios := spectral.ParticipationRatios(vectors)
+	for i, value := range values {
+		Log.Debugln(i, value, cmplx.Abs(value), cmplx.Phase(value), ratios[i])
+	}
+	Log.Debugf("\n")
+	DumpPolar(vectors, values)
+	Log.Debugf("\n")
+	if *FlagEigenOutput != "" {
+		if err := WriteEigen(*FlagEigenOutput, vectors, values); err != nil {
+			return err
+		}
+	}
+
+	scores := make([]float64, size)
+	for node := range scores {
+		scores[node] = cmplx.Abs(vectors.At(node, spectrum.Dominant))
 	}
+	for i, node := range TopK(spectral.RankScores(scores), *FlagTopK) {
+		Log.Infoln(i, node, scores[node])
+	}
+	return nil
 }
 
-func main() {
+// GenerateFlags returns the random graph options of the -generate flags
+func GenerateFlags() GenerateOptions {
+	return GenerateOptions{
+		Model: *FlagGenerate,
+		Size:  *FlagSize,
+		P:     *FlagGrP,
+		M:     *FlagGrM,
+		K:     *FlagGrK,
+	}
+}
+
+// LossFlag returns the loss named by -loss, an unknown loss falls back to quadratic with a warning
+func LossFlag() string {
+	for _, name := range Losses {
+		if *FlagLoss == name {
+			return name
+		}
+	}
+	Log.Warnf("unknown loss %s, using quadratic, supported losses are %s",
+		*FlagLoss, strings.Join(Losses, ", "))
+	return "quadratic"
+}
+
+// run runs the analysis selected by the flags, it returns the error instead of exiting so that
+// the deferred cpu profile and timings are written
+func run() error {
 	flag.Parse()
-	rand.Seed(1)
+	if *FlagConfig != "" {
+		config, err := LoadConfig(*FlagConfig)
+		if err == nil {
+			err = config.Apply(flag.CommandLine)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// the flags are validated before dispatching to a mode or touching a file
+	if err := ValidateFlags(); err != nil {
+		return err
+	}
+	Log.Level = *FlagVerbose
+	manifest := NewManifest(flag.CommandLine)
+	// an interrupt stops the power iteration, the neural training and the batch early, a second
+	// interrupt exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	if *FlagOutputDir != "" {
+		PrefixOutputs(*FlagOutputDir)
+		// a dry run only checks that the directory can be created
+		if !*FlagDryRun {
+			if err := os.MkdirAll(*FlagOutputDir, 0755); err != nil {
+				return err
+			}
+		}
+	}
+	if *FlagDryRun {
+		if err := DryRun(os.Stdout); err != nil {
+			return err
+		}
+		return nil
+	}
+	if *FlagProfile != "" {
+		profile, err := os.Create(*FlagProfile)
+		if err == nil {
+			err = pprof.StartCPUProfile(profile)
+		}
+		if err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	}
+	var timer *Timer
+	if *FlagTiming {
+		timer = NewTimer()
+		defer timer.Print(os.Stderr)
+	}
+	seed := *FlagSeed
+	if seed == -1 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	manifest.SetSeed(seed)
+	if *FlagManifest != "" && (*FlagComplexInput != "" || *FlagInputDir != "" || *FlagStream) {
+		Log.Warnf("-manifest is only written for the analysis of a single graph")
+	}
 
-	data := []float64{
-		0, 1, 0, 1, 1,
-		1, 0, 1, 0, 1,
-		0, 1, 0, 1, 1,
-		1, 0, 1, 0, 1,
-		1, 1, 1, 1, 1,
+	if *FlagComplexInput != "" {
+		if err := ComplexInput(*FlagComplexInput, *FlagMaxSize); err != nil {
+			return err
+		}
+		timer.Mark("complex eigendecomposition")
+		return nil
 	}
-	adjacency := mat.NewDense(Size, Size, data)
-	var eig mat.Eigen
-	ok := eig.Factorize(adjacency, mat.EigenRight)
-	if !ok {
-		panic("Eigendecomposition failed")
+
+	if *FlagStream {
+		err := Stream(ctx, os.Stdin, Log.Writer(LevelInfo), StreamOptions{
+			Directed:   *FlagDirected,
+			Interval:   time.Duration(*FlagInterval * float64(time.Second)),
+			Every:      *FlagStreamEvery,
+			TopK:       *FlagTopK,
+			Iterations: *FlagPowerIterations,
+			Tol:        *FlagPowerTol,
+			MaxSize:    *FlagMaxSize,
+		})
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 
-	values := eig.Values(nil)
-	for i, value := range values {
-		fmt.Println(i, value, cmplx.Abs(value), cmplx.Phase(value))
+	if *FlagInputDir != "" {
+		graphs, errs := Batch(ctx, *FlagInputDir, BatchOptions{
+			PipelineOptions: PipelineFlags(),
+			Workers:         *FlagWorkers,
+			OutputDir:       *FlagOutputDir,
+			MaxSize:         *FlagMaxSize,
+		})
+		timer.Mark("batch")
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		Log.Infof("analyzed %d graphs, %d failed\n", graphs, len(errs))
+		if len(errs) > 0 {
+			return fmt.Errorf("%s: %d failed", *FlagInputDir, len(errs))
+		}
+		return nil
 	}
-	fmt.Printf("\n")
 
-	vectors := mat.CDense{}
-	eig.VectorsTo(&vectors)
-	for i := 0; i < Size; i++ {
-		for j := 0; j < Size; j++ {
-			fmt.Printf("%f ", vectors.At(i, j))
+	var (
+		adjacency *mat.Dense
+		names     []string
+		err       error
+	)
+	directed := *FlagDirected
+	// power iteration doesn't eigendecompose the matrix, so it isn't limited by -max-size
+	maxSize := *FlagMaxSize
+	if *FlagPower {
+		maxSize = 0
+	}
+	// the sparse path only supports power iteration on the raw adjacency matrix
+	if *FlagEdgeList != "" && *FlagPower && (*FlagNormalize == "" || *FlagNormalize == "none") &&
+		!*FlagLaplacian && *FlagSelfLoops && !*FlagStrict && *FlagDOTOutput == "" && *FlagThreshold <= 0 {
+		sparse, err := LoadEdgeListSparse(*FlagEdgeList, directed)
+		if err != nil {
+			return err
+		}
+		timer.Mark("load")
+		if *FlagManifest != "" {
+			manifest.HashCSR(sparse)
+			if err := WriteManifest(manifest); err != nil {
+				return err
+			}
+		}
+		if sparse.Density() < SparseDensity {
+			if err := SparsePower(ctx, sparse); err != nil {
+				return err
+			}
+			timer.Mark("sparse power iteration")
+			return nil
+		}
+		adjacency = sparse.Dense()
+	}
+	switch {
+	case adjacency != nil:
+	case *FlagInput != "":
+		adjacency, err = LoadCSV(*FlagInput)
+	case *FlagEdgeList != "":
+		adjacency, err = LoadEdgeList(*FlagEdgeList, directed, maxSize)
+	case *FlagGraphML != "":
+		adjacency, names, directed, err = LoadGraphML(*FlagGraphML)
+	case *FlagDOT != "":
+		adjacency, names, directed, err = LoadDOT(*FlagDOT)
+	case *FlagGenerate != "":
+		options := GenerateFlags()
+		if err = CheckSize(options.Size, maxSize); err == nil {
+			adjacency, err = Generate(rng, options)
+		}
+		if err == nil {
+			Log.Infoln("generated", options)
+			Log.Infof("\n")
+		}
+	default:
+		adjacency, err = Demo(*FlagSize)
+	}
+	if err == nil {
+		size, _ := adjacency.Dims()
+		err = CheckSize(size, maxSize)
+	}
+	if errors.Is(err, ErrTooLarge) {
+		err = fmt.Errorf("%w\nuse -power for power iteration instead, it runs on a sparse matrix when the -edgelist graph is sparse", err)
+	}
+	if err != nil {
+		return err
+	}
+	timer.Mark("load")
+	if *FlagManifest != "" && manifest.Matrix == nil {
+		manifest.HashDense(adjacency)
+		if err := WriteManifest(manifest); err != nil {
+			return err
 		}
-		fmt.Printf("\n")
 	}
-	fmt.Printf("\n")
+	loss := LossFlag()
+
+	size, _ := adjacency.Dims()
+	debug := Log.Writer(LevelDebug)
+	if *FlagQuiet {
+		debug = ioutil.Discard
+	}
+	pipeline := &Pipeline{
+		PipelineOptions: PipelineFlags(),
+		Output:          Log.Writer(LevelInfo),
+		Warnings:        Log.Writer(LevelWarning),
+		Debug:           debug,
+	}
+	pipeline.Directed = directed
+	components, err := pipeline.Clean(adjacency)
+	if err != nil {
+		return err
+	}
+
+	// the community detection, the bisection and the dot output use the graph before normalizing
+	unnormalized := adjacency
+	adjacency, err = pipeline.Prepare(adjacency, components)
+	if err != nil {
+		return err
+	}
+
+	// a single node is degenerate and isn't projected
+	if size > 1 && (*FlagComponents < 1 || *FlagComponents > size) {
+		return fmt.Errorf("-components must be between 1 and %d", size)
+	}
+
+	labels, err := LoadLabels(*FlagLabels, size)
+	if err != nil {
+		return err
+	}
+	if *FlagLabels == "" && names != nil {
+		labels = names
+	}
+	// the ranking output only has a name column when the nodes are named
+	var named []string
+	if *FlagLabels != "" || names != nil {
+		named = labels
+	}
+	metadata, err := LoadMetadata(*FlagMetadata, labels)
+	if err != nil {
+		return err
+	}
+	if metadata != nil && *FlagRankingOutput == "" {
+		Log.Warnf("-metadata is only joined into -ranking-output")
+	}
 
-	for i := 0; i < Size; i++ {
-		for j := 0; j < Size; j++ {
-			fmt.Printf("(%f, %f) ", cmplx.Abs(vectors.At(i, j)), cmplx.Phase(vectors.At(i, j)))
+	if *FlagHeatMap != "" {
+		magnitudes := make([]float64, 0, size*size)
+		for i := 0; i < size; i++ {
+			for j := 0; j < size; j++ {
+				magnitudes = append(magnitudes, math.Abs(adjacency.At(i, j)))
+			}
+		}
+		err := HeatMap(PlotOptions{
+			Name:    HeatMapName(*FlagHeatMap, "adjacency"),
+			Title:   "adjacency",
+			X:       "column",
+			Y:       "row",
+			Width:   *FlagPlotWidth,
+			Height:  *FlagPlotHeight,
+			Palette: *FlagPalette,
+		}, Grid{Size: size, Values: magnitudes}, 0, Scale(adjacency, magnitudes))
+		if err != nil {
+			return err
 		}
-		fmt.Printf("\n")
 	}
 
-	if *FlagNeural {
-		Neural(&vectors, values)
+	timer.Mark("preprocessing")
+
+	// the bipartite ranking uses the graph before normalizing, the laplacian has self-loops
+	color, bipartite := spectral.Bipartite(unnormalized)
+	if *FlagBipartite {
+		if !bipartite {
+			return errors.New("-bipartite requires a bipartite graph, the graph has an odd cycle or a self-loop")
+		}
+		ranking, err := spectral.SVDRank(unnormalized, color)
+		if err != nil {
+			return err
+		}
+		Log.Infoln("singular value", ranking.Value)
+		partitions := []struct {
+			Name   string
+			Nodes  []int
+			Scores []float64
+		}{
+			{"left partition", ranking.Left, ranking.LeftScores},
+			{"right partition", ranking.Right, ranking.RightScores},
+		}
+		for _, partition := range partitions {
+			Log.Infof("\n")
+			Log.Infoln(partition.Name)
+			for i, k := range TopK(spectral.RankScores(partition.Scores), *FlagTopK) {
+				Log.Infoln(i, partition.Nodes[k], partition.Scores[k])
+			}
+		}
+		timer.Mark("singular value decomposition")
+		return nil
+	}
+	if bipartite && size > 1 {
+		Log.Infoln("the graph is bipartite, -bipartite ranks each partition by the singular vectors of the biadjacency matrix")
+		Log.Infof("\n")
 	}
 
-	ranks := mat.NewDense(Size, Size, nil)
-	for i := 0; i < Size; i++ {
-		for j := 0; j < Size; j++ {
-			ranks.Set(i, j, real(vectors.At(i, j)))
+	if *FlagPower {
+		value, vector, convergence, err := spectral.PowerIteration(ctx, adjacency, *FlagPowerIterations, *FlagPowerTol)
+		if err != nil {
+			return err
 		}
+		Log.Infoln(value)
+		PrintConvergence(convergence)
+		Log.Infof("\n")
+		scores := make([]float64, len(vector))
+		for i, v := range vector {
+			scores[i] = math.Abs(v)
+		}
+		for i, node := range TopK(spectral.RankScores(scores), *FlagTopK) {
+			Log.Infoln(i, node, scores[node])
+		}
+		if *FlagRankingOutput != "" {
+			err := WriteRankings(*FlagRankingOutput, named, metadata, []Ranking{{Method: "power", Scores: scores}})
+			if err != nil {
+				return err
+			}
+		}
+		if *FlagDOTOutput != "" {
+			err := WriteDOT(*FlagDOTOutput, unnormalized, labels, scores, directed)
+			if err != nil {
+				return err
+			}
+		}
+		timer.Mark("power iteration")
+		return nil
+	}
+
+	graph := pipeline.Graph(adjacency)
+	graph.PCAMode = *FlagPCAMode
+	graph.PCAMatrix = *FlagPCAMatrix
+	if *FlagPCAWeights != "" {
+		graph.PCAWeights, err = LoadPCAWeights(*FlagPCAWeights, size)
+		if err != nil {
+			return err
+		}
+	}
+	if *FlagHeatT > 0 {
+		// the heat kernel diffuses on the graph before normalizing like the bisection
+		graph.Embedding, err = spectral.HeatKernel(unnormalized, *FlagHeatT)
+		if err != nil {
+			return err
+		}
+		timer.Mark("heat kernel")
+	}
+	spectrum, err := graph.Spectrum()
+	if err != nil {
+		return err
+	}
+	vectors, values := spectrum.Vectors, spectrum.Values
+	timer.Mark("eigendecomposition")
+	if *FlagEigenOutput != "" {
+		err := WriteEigen(*FlagEigenOutput, vectors, values)
+		if err != nil {
+			return err
+		}
+	}
+	pipeline.Summarize(spectrum)
+
+	if !*FlagQuiet {
+		DumpVectors(vectors, values)
+		Log.Debugf("\n")
+		DumpPolar(vectors, values)
+		Log.Debugf("\n")
+		if left := spectrum.Left; left != nil && *FlagEigenSide == "both" {
+			Log.Debugln("left eigenvectors")
+			DumpVectors(left, values)
+			Log.Debugf("\n")
+		}
+	}
+
+	if err := spectral.Degenerate(adjacency); err != nil {
+		Log.Warnf("%v, the ranking, neural training and projection are skipped", err)
+		return nil
+	}
+
+	dominant := spectrum.Dominant
+	rankings, err := pipeline.Rank(graph, spectrum)
+	if err != nil {
+		return err
+	}
+	// the dot output and the projection json are labeled with the page rank scores with -pagerank
+	// and with the dominant eigenvector magnitudes otherwise
+	scores := rankings.Magnitudes
+	if rankings.PageRank != nil {
+		scores = rankings.PageRank
+	}
+
+	if *FlagRankingOutput != "" {
+		if err := WriteRankings(*FlagRankingOutput, named, metadata, rankings.List); err != nil {
+			return err
+		}
+	}
+
+	if *FlagCommunities {
+		count, communities := spectral.Communities(unnormalized)
+		Log.Infof("\n")
+		Log.Infoln("communities", count)
+		Log.Infoln("modularity", spectral.Modularity(unnormalized, communities))
+		for node, community := range communities {
+			Log.Infoln(node, community)
+		}
+	}
+
+	if *FlagFiedler {
+		value, fiedler, err := spectral.Fiedler(unnormalized)
+		if err != nil {
+			return err
+		}
+		partition, cut := spectral.Bisect(unnormalized, fiedler)
+		parts := [2][]int{}
+		for node, part := range partition {
+			parts[part] = append(parts[part], node)
+		}
+		Log.Infof("\n")
+		Log.Infoln("fiedler value", value)
+		Log.Infoln("edge cut", cut)
+		for part, nodes := range parts {
+			Log.Infoln("partition", part, nodes)
+		}
+	}
+
+	if *FlagCompare {
+		compared := []Ranking{
+			{Method: "spectral", Scores: rankings.Magnitudes},
+			{Method: "eigenvector", Scores: rankings.Centrality},
+			{Method: "pagerank", Scores: spectral.PageRank(adjacency, *FlagDamping, 1e-12, 1000)},
+		}
+		katz, err := spectral.Katz(adjacency, *FlagKatzAlpha, 1)
+		if err != nil {
+			Log.Warnf("%v", err)
+		} else {
+			compared = append(compared, Ranking{Method: "katz", Scores: katz})
+		}
+
+		output := Log.Writer(LevelInfo)
+		if *FlagCompareOutput != "" {
+			file, err := os.Create(*FlagCompareOutput)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			output = file
+		} else {
+			Log.Infof("\n")
+		}
+		Compare(output, labels, compared)
+	}
+
+	if *FlagPhasePlot != "" {
+		points := make(plotter.XYs, 0, size)
+		for node := 0; node < size; node++ {
+			v := vectors.At(node, dominant)
+			points = append(points, plotter.XY{X: real(v), Y: imag(v)})
+		}
+		err := Scatter(PlotOptions{
+			Name:   *FlagPhasePlot,
+			Title:  "dominant eigenvector on the complex plane",
+			X:      "real",
+			Y:      "imaginary",
+			Width:  *FlagPlotWidth,
+			Height: *FlagPlotHeight,
+			Labels: labels,
+		}, 3, points)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *FlagDOTOutput != "" {
+		err := WriteDOT(*FlagDOTOutput, unnormalized, labels, scores, directed)
+		if err != nil {
+			return err
+		}
+	}
+
+	timer.Mark("ranking")
+
+	if *FlagNeural {
+		Log.Infof("\n")
+		Log.Infoln("seed", seed)
+		Log.Infoln("loss", loss)
+		neural := Neural
+		costTitle := *FlagCostTitle
+		if *FlagLogCost && costTitle == "epochs vs cost" {
+			costTitle += " (log scale)"
+		}
+		if *FlagReal {
+			if !spectral.IsSymmetric(adjacency, spectral.Tolerance) {
+				return errors.New("-real requires a symmetric adjacency matrix")
+			}
+			neural = NeuralReal
+		}
+		err = neural(ctx, rng, size, vectors, values, NeuralOptions{
+			Eta:          *FlagEta,
+			Iterations:   *FlagIterations,
+			Optimizer:    *FlagOptimizer,
+			Beta1:        *FlagBeta1,
+			Beta2:        *FlagBeta2,
+			Epsilon:      *FlagEpsilon,
+			Tol:          *FlagTol,
+			Patience:     *FlagPatience,
+			SaveWeights:  *FlagSaveWeights,
+			LoadWeights:  *FlagLoadWeights,
+			L2:           *FlagL2,
+			Layers:       *FlagLayers,
+			Clip:         *FlagClip,
+			Batch:        *FlagBatch,
+			Momentum:     *FlagMomentum,
+			Loss:         loss,
+			LogEvery:     *FlagLogEvery,
+			ProgressBar:  *FlagProgress,
+			CostData:     *FlagCostData,
+			Learned:      *FlagLearnedOutput,
+			Adjacency:    adjacency,
+			ValSplit:     *FlagValSplit,
+			Restarts:     *FlagRestarts,
+			Gradient:     *FlagGradientOutput,
+			Animate:      *FlagAnimate,
+			AnimateEvery: *FlagAnimateEvery,
+			HeatMap:      HeatMapName(*FlagHeatMap, "learned"),
+			Init:         *FlagInit,
+			CostPlot: PlotOptions{
+				Name:    *FlagCostPlot,
+				Title:   costTitle,
+				X:       *FlagCostX,
+				Y:       *FlagCostY,
+				Width:   *FlagPlotWidth,
+				Height:  *FlagPlotHeight,
+				LogY:    *FlagLogCost,
+				Palette: *FlagPalette,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		timer.Mark("neural training")
+	}
+
+	err = Reduction(graph, ReductionOptions{
+		Components: *FlagComponents,
+		Plot: PlotOptions{
+			Name:    *FlagVectorsPlot,
+			Title:   *FlagVectorsTitle,
+			X:       *FlagVectorsX,
+			Y:       *FlagVectorsY,
+			Width:   *FlagPlotWidth,
+			Height:  *FlagPlotHeight,
+			Labels:  labels,
+			Palette: *FlagPalette,
+		},
+		Data:              *FlagVectorsData,
+		JSON:              *FlagVectorsJSON,
+		Scores:            scores,
+		Clusters:          *FlagClusters,
+		Quiet:             *FlagQuiet,
+		Timer:             timer,
+		Similarity:        *FlagSimilarity,
+		SimilarityHeatMap: HeatMapName(*FlagHeatMap, "similarity"),
+	})
+	if err != nil {
+		return err
+	}
+	timer.Mark("projection output")
+	//NeuralReduction(rng, "neural", size, vectors)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	Reduction("results", ranks)
-	//NeuralReduction("neural", &vectors)
 }
@@ -0,0 +1,184 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// Version and Commit identify the build in the manifest, they are set with
+// go build -ldflags "-X main.Version=v1.0.0 -X main.Commit=$(git rev-parse HEAD)"
+var (
+	Version = ""
+	Commit  = ""
+)
+
+// InputSource returns the format and the name of the input file in the order main loads them,
+// the name is empty when the graph is generated
+func InputSource() (string, string) {
+	sources := []struct {
+		Format string
+		Name   string
+	}{
+		{"csv", *FlagInput},
+		{"edge list", *FlagEdgeList},
+		{"graphml", *FlagGraphML},
+		{"dot", *FlagDOT},
+	}
+	for _, source := range sources {
+		if source.Name != "" {
+			return source.Format, source.Name
+		}
+	}
+	return "", ""
+}
+
+// ManifestInput is the input file of a run, SHA256 is the hash of the file contents and is
+// empty for standard input, which can't be read twice
+type ManifestInput struct {
+	Format string `json:"format"`
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// ManifestMatrix is the adjacency matrix as loaded, before it is validated and normalized. SHA256
+// is the hash of the row, column and value of every nonzero entry in row major order, each as 8
+// little endian bytes, so that the hash of a dense and a sparse matrix is the same.
+type ManifestMatrix struct {
+	Size    int    `json:"size"`
+	NonZero int    `json:"nonzero"`
+	SHA256  string `json:"sha256"`
+}
+
+// Manifest records what is needed to reproduce a run
+type Manifest struct {
+	Version string   `json:"version"`
+	Commit  string   `json:"commit,omitempty"`
+	Go      string   `json:"go"`
+	Time    string   `json:"time"`
+	Args    []string `json:"args"`
+	// Flags is the value of every flag but -config with the seed that was used, it can be
+	// passed to -config to rerun the analysis
+	Flags  map[string]string `json:"flags"`
+	Seed   int64             `json:"seed"`
+	Input  *ManifestInput    `json:"input,omitempty"`
+	Matrix *ManifestMatrix   `json:"matrix,omitempty"`
+}
+
+// NewManifest records the build, the command line and the flags, which have to be recorded
+// before -output-dir prefixes the output files
+func NewManifest(flags *flag.FlagSet) *Manifest {
+	manifest := &Manifest{
+		Version: Version,
+		Commit:  Commit,
+		Go:      runtime.Version(),
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Args:    os.Args[1:],
+		Flags:   make(map[string]string),
+	}
+	if manifest.Version == "" {
+		manifest.Version = "(devel)"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			manifest.Version = info.Main.Version
+		}
+	}
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" {
+			manifest.Flags[f.Name] = f.Value.String()
+		}
+	})
+	return manifest
+}
+
+// SetSeed records the seed, which replaces a -seed of -1 so that the run can be repeated
+func (m *Manifest) SetSeed(seed int64) {
+	m.Seed = seed
+	m.Flags["seed"] = strconv.FormatInt(seed, 10)
+}
+
+// HashInput records the input file and the hash of its contents
+func (m *Manifest) HashInput(format, name string) error {
+	m.Input = &ManifestInput{Format: format, Name: name}
+	if name == "-" {
+		return nil
+	}
+	input, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, input); err != nil {
+		return err
+	}
+	m.Input.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// hashEntry adds a nonzero entry of the adjacency matrix to the hash
+func hashEntry(h hash.Hash, i, j int, v float64) {
+	var buffer [24]byte
+	binary.LittleEndian.PutUint64(buffer[0:], uint64(i))
+	binary.LittleEndian.PutUint64(buffer[8:], uint64(j))
+	binary.LittleEndian.PutUint64(buffer[16:], math.Float64bits(v))
+	h.Write(buffer[:])
+}
+
+// HashDense records the size and the hash of a dense adjacency matrix
+func (m *Manifest) HashDense(a *mat.Dense) {
+	size, _ := a.Dims()
+	h, nonzero := sha256.New(), 0
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if v := a.At(i, j); v != 0 {
+				hashEntry(h, i, j, v)
+				nonzero++
+			}
+		}
+	}
+	m.Matrix = &ManifestMatrix{Size: size, NonZero: nonzero, SHA256: hex.EncodeToString(h.Sum(nil))}
+}
+
+// HashCSR records the size and the hash of a sparse adjacency matrix
+func (m *Manifest) HashCSR(c *spectral.CSR) {
+	h, nonzero := sha256.New(), 0
+	for i := 0; i < c.Size; i++ {
+		for k := c.Offsets[i]; k < c.Offsets[i+1]; k++ {
+			if v := c.Values[k]; v != 0 {
+				hashEntry(h, i, c.Columns[k], v)
+				nonzero++
+			}
+		}
+	}
+	m.Matrix = &ManifestMatrix{Size: c.Size, NonZero: nonzero, SHA256: hex.EncodeToString(h.Sum(nil))}
+}
+
+// Write writes the manifest to a json file
+func (m *Manifest) Write(name string) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
@@ -0,0 +1,331 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// Silence discards the output of Log until the test ends, Log keeps the os.Stdout of when the
+// package was initialized so its writers are replaced
+func Silence(tb testing.TB) {
+	stdout, stderr := Log.Output, Log.Errors
+	Log.Output, Log.Errors = ioutil.Discard, ioutil.Discard
+	tb.Cleanup(func() {
+		Log.Output, Log.Errors = stdout, stderr
+	})
+}
+
+// KnownGraphs are small undirected graphs with known spectra
+var KnownGraphs = map[string][][]float64{
+	// the eigenvalues of the triangle are 2, -1 and -1
+	"triangle": {
+		{0, 1, 1},
+		{1, 0, 1},
+		{1, 1, 0},
+	},
+	// the eigenvalues of the path of 3 nodes are √2, 0 and -√2
+	"path": {
+		{0, 1, 0},
+		{1, 0, 1},
+		{0, 1, 0},
+	},
+	// the eigenvalues of the star with 3 leaves are √3, 0, 0 and -√3
+	"star": {
+		{0, 1, 1, 1},
+		{1, 0, 0, 0},
+		{1, 0, 0, 0},
+		{1, 0, 0, 0},
+	},
+}
+
+// KnownGraph returns the adjacency matrix of one of the KnownGraphs
+func KnownGraph(name string) *mat.Dense {
+	rows := KnownGraphs[name]
+	adjacency := mat.NewDense(len(rows), len(rows), nil)
+	for i, row := range rows {
+		adjacency.SetRow(i, row)
+	}
+	return adjacency
+}
+
+// EqualFloats determines if the slices have the same length and their elements are within tolerance
+func EqualFloats(a, b []float64, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEigenpairs(t *testing.T) {
+	// the eigenvectors of the edge are (1, 1) and (1, -1) with the eigenvalues 1 and -1, the
+	// second column is scaled by i to check that the imaginary parts follow the real parts
+	vectors := mat.NewCDense(2, 2, []complex128{
+		1, 1i,
+		1, -1i,
+	})
+	values := []complex128{1, -1}
+	cases := []struct {
+		columns []int
+		inputs  []float64
+		targets []float64
+	}{
+		{[]int{0}, []float64{1, 1, 0, 0}, []float64{1, 1, 0, 0}},
+		{[]int{1}, []float64{0, 0, 1, -1}, []float64{0, 0, -1, 1}},
+		{[]int{1, 0}, []float64{0, 0, 1, -1, 1, 1, 0, 0}, []float64{0, 0, -1, 1, 1, 1, 0, 0}},
+		{nil, []float64{}, []float64{}},
+	}
+	for _, c := range cases {
+		inputs, targets := Eigenpairs(vectors, values, c.columns)
+		if !EqualFloats(inputs, c.inputs, 0) {
+			t.Errorf("columns %v: inputs %v, expected %v", c.columns, inputs, c.inputs)
+		}
+		if !EqualFloats(targets, c.targets, 0) {
+			t.Errorf("columns %v: targets %v, expected %v", c.columns, targets, c.targets)
+		}
+	}
+}
+
+// NeuralTestOptions are the options neural mode is tested with
+var NeuralTestOptions = NeuralOptions{
+	Eta:        .1,
+	Iterations: 2000,
+	Optimizer:  "sgd",
+	Layers:     1,
+	Loss:       "quadratic",
+}
+
+// NewTestNetwork builds the network of a known graph with complex or real weights
+func NewTestNetwork(t *testing.T, name string, realWeights bool, options NeuralOptions) (Network, *mat.Dense) {
+	return NewAdjacencyNetwork(t, KnownGraph(name), realWeights, options)
+}
+
+// NewDemoNetwork builds the network of the demo graph with complex or real weights
+func NewDemoNetwork(t *testing.T, realWeights bool, options NeuralOptions) (Network, *mat.Dense) {
+	adjacency, err := Demo(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAdjacencyNetwork(t, adjacency, realWeights, options)
+}
+
+// NewAdjacencyNetwork builds the network of an adjacency matrix with complex or real weights
+func NewAdjacencyNetwork(t *testing.T, adjacency *mat.Dense, realWeights bool, options NeuralOptions) (Network, *mat.Dense) {
+	size, _ := adjacency.Dims()
+	vectors, values, err := spectral.NewGraph(adjacency).Eigen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	var network Network
+	if realWeights {
+		network, err = NewRealNetwork(rng, size, vectors, values, options)
+	} else {
+		network, err = NewComplexNetwork(rng, size, vectors, values, options)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return network, adjacency
+}
+
+func TestNeural(t *testing.T) {
+	Silence(t)
+	// the cost is over every eigenpair, so it only goes to zero when the learned matrix has all
+	// of them and not only the dominant one
+	for _, name := range []string{"triangle", "path", "star"} {
+		for _, realWeights := range []bool{false, true} {
+			network, _ := NewTestNetwork(t, name, realWeights, NeuralTestOptions)
+			initial := network.Cost()
+			cost, epochs, _, err := Train(context.Background(), network, NeuralTestOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if epochs != NeuralTestOptions.Iterations {
+				t.Errorf("%s real %t: %d epochs, expected %d", name, realWeights, epochs, NeuralTestOptions.Iterations)
+			}
+			if final := network.Cost(); !(cost < initial) || !(final < 1e-6*initial) {
+				t.Errorf("%s real %t: the cost went from %g to %g", name, realWeights, initial, final)
+			}
+		}
+	}
+}
+
+func TestAdam(t *testing.T) {
+	Silence(t)
+	const tolerance = 1e-6
+	// steps returns the number of iterations until the cost of the demo network is below tolerance,
+	// both optimizers start from the weights of the same seed with the same learning rate
+	steps := func(optimizer string, realWeights bool) int {
+		options := NeuralTestOptions
+		options.Optimizer, options.Beta1, options.Beta2, options.Epsilon = optimizer, .9, .999, 1e-8
+		network, _ := NewDemoNetwork(t, realWeights, options)
+		network.Reset()
+		for i := 0; i < options.Iterations; i++ {
+			if cost, _, _, _ := network.Gradient(); cost < tolerance {
+				return i
+			}
+			network.Update(i, 1)
+		}
+		t.Fatalf("%s real %t: the cost isn't below %g after %d iterations", optimizer, realWeights, tolerance, options.Iterations)
+		return 0
+	}
+	for _, realWeights := range []bool{false, true} {
+		if sgd, adam := steps("sgd", realWeights), steps("adam", realWeights); !(adam < sgd) {
+			t.Errorf("real %t: adam took %d iterations and sgd %d, expected adam to take fewer", realWeights, adam, sgd)
+		}
+	}
+}
+
+func TestTrainLayers(t *testing.T) {
+	Silence(t)
+	options := NeuralTestOptions
+	options.Layers, options.Iterations = 3, 100
+	for _, realWeights := range []bool{false, true} {
+		network, _ := NewTestNetwork(t, "triangle", realWeights, options)
+		layers, weights := network.Weights()
+		if !reflect.DeepEqual(layers, []string{"A", "A1", "A2"}) {
+			t.Errorf("real %t: the trained set has the weights %v, expected only the layers", realWeights, layers)
+		}
+		before := make([][]complex128, len(weights))
+		for l, w := range weights {
+			before[l] = append([]complex128(nil), w...)
+		}
+		initial := network.Cost()
+		if _, _, _, err := Train(context.Background(), network, options); err != nil {
+			t.Fatal(err)
+		}
+		_, weights = network.Weights()
+		for l, w := range weights {
+			changed := 0
+			for i := range w {
+				if w[i] != before[l][i] {
+					changed++
+				}
+			}
+			if changed != len(w) {
+				t.Errorf("real %t: %d of the %d weights of layer %s changed, expected every weight", realWeights, changed, len(w), layers[l])
+			}
+		}
+		if final := network.Cost(); !(final < initial) {
+			t.Errorf("real %t: the cost went from %g to %g", realWeights, initial, final)
+		}
+	}
+}
+
+func TestReconstruction(t *testing.T) {
+	adjacency := KnownGraph("triangle")
+	exact := make([]complex128, 9)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			exact[i*3+j] = complex(adjacency.At(i, j), 0)
+		}
+	}
+	imaginary := append([]complex128(nil), exact...)
+	imaginary[1] += 5i
+	shifted := append([]complex128(nil), exact...)
+	shifted[4] += 3
+	shifted[5] -= 4
+	cases := []struct {
+		name    string
+		weights []complex128
+		err     float64
+	}{
+		{"exact", exact, 0},
+		// only the real part of the weights is compared
+		{"imaginary", imaginary, 0},
+		{"shifted", shifted, 5},
+		{"zero", make([]complex128, 9), math.Sqrt(6)},
+	}
+	for _, c := range cases {
+		if err := Reconstruction(adjacency, c.weights); math.Abs(err-c.err) > 1e-12 {
+			t.Errorf("%s: reconstruction error %g, expected %g", c.name, err, c.err)
+		}
+	}
+
+	// the eigenvectors of a symmetric graph are a basis, so the only matrix with every eigenpair
+	// is the adjacency matrix and training reconstructs it
+	Silence(t)
+	for _, name := range []string{"triangle", "path", "star"} {
+		for _, realWeights := range []bool{false, true} {
+			network, adjacency := NewTestNetwork(t, name, realWeights, NeuralTestOptions)
+			if _, _, _, err := Train(context.Background(), network, NeuralTestOptions); err != nil {
+				t.Fatal(err)
+			}
+			_, weights := network.Weights()
+			if err := Reconstruction(adjacency, weights[0]); err > 1e-3 {
+				t.Errorf("%s real %t: reconstruction error %g", name, realWeights, err)
+			}
+		}
+	}
+
+	// with the same seed a longer training of the demo graph starts the same way and keeps
+	// descending, so the reconstruction error goes down with every doubling of the iterations
+	for _, realWeights := range []bool{false, true} {
+		previous := math.Inf(1)
+		for _, iterations := range []int{25, 50, 100, 200, 400, 800} {
+			options := NeuralTestOptions
+			options.Iterations = iterations
+			network, adjacency := NewDemoNetwork(t, realWeights, options)
+			if _, _, _, err := Train(context.Background(), network, options); err != nil {
+				t.Fatal(err)
+			}
+			_, weights := network.Weights()
+			err := Reconstruction(adjacency, weights[0])
+			if !(err < previous) {
+				t.Errorf("demo real %t: reconstruction error %g after %d iterations, expected less than %g", realWeights, err, iterations, previous)
+			}
+			previous = err
+		}
+		if previous > 1e-6 {
+			t.Errorf("demo real %t: reconstruction error %g after 800 iterations", realWeights, previous)
+		}
+	}
+}
+
+func TestTrainCanceled(t *testing.T) {
+	Silence(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, realWeights := range []bool{false, true} {
+		network, _ := NewTestNetwork(t, "triangle", realWeights, NeuralTestOptions)
+		_, before := network.Weights()
+		initial := append([]complex128(nil), before[0]...)
+		_, epochs, _, err := Train(ctx, network, NeuralTestOptions)
+		if !errors.Is(err, spectral.ErrCanceled) || epochs != 0 {
+			t.Errorf("real %t: %d epochs with the error %v, expected 0 epochs with %v", realWeights, epochs, err, spectral.ErrCanceled)
+		}
+
+		// the outputs are written with the partially trained weights before the error is returned
+		options := NeuralTestOptions
+		options.Learned = filepath.Join(t.TempDir(), "learned.csv")
+		if err := RunNetwork(ctx, network, 3, options); !errors.Is(err, spectral.ErrCanceled) {
+			t.Errorf("real %t: error %v, expected %v", realWeights, err, spectral.ErrCanceled)
+		}
+		if _, weights := network.Weights(); !reflect.DeepEqual(weights[0], initial) {
+			t.Errorf("real %t: the weights changed after the context was done", realWeights)
+		}
+		if _, err := os.Stat(options.Learned); err != nil {
+			t.Errorf("real %t: the learned weights weren't written: %v", realWeights, err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"math/cmplx"
+	"os"
+	"strconv"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// Complex is a complex number in json
+type Complex struct {
+	Real float64 `json:"real"`
+	Imag float64 `json:"imag"`
+}
+
+// EigenOutput is the eigendecomposition in json, Vectors[k] is the eigenvector of Values[k]
+// and the eigenpairs are sorted by descending eigenvalue magnitude
+type EigenOutput struct {
+	Values  []Complex   `json:"values"`
+	Vectors [][]Complex `json:"vectors"`
+}
+
+// WriteEigen writes the eigendecomposition to a json file
+func WriteEigen(name string, vectors *mat.CDense, values []complex128) error {
+	rows, _ := vectors.Dims()
+	output := EigenOutput{
+		Values:  make([]Complex, 0, len(values)),
+		Vectors: make([][]Complex, 0, len(values)),
+	}
+	for k, value := range values {
+		output.Values = append(output.Values, Complex{Real: real(value), Imag: imag(value)})
+		vector := make([]Complex, 0, rows)
+		for i := 0; i < rows; i++ {
+			v := vectors.At(i, k)
+			vector = append(vector, Complex{Real: real(v), Imag: imag(v)})
+		}
+		output.Vectors = append(output.Vectors, vector)
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// WriteLearned writes the learned weights to a csv file with a row per entry of each layer,
+// the complex value is split into its real and imaginary parts next to its magnitude
+func WriteLearned(name string, size int, layers []string, weights [][]complex128) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	output := csv.NewWriter(file)
+	output.Write([]string{"layer", "row", "column", "real", "imag", "magnitude"})
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	for l, layer := range layers {
+		for i := 0; i < size; i++ {
+			for j := 0; j < size; j++ {
+				value := weights[l][i*size+j]
+				output.Write([]string{layer, strconv.Itoa(i), strconv.Itoa(j),
+					format(real(value)), format(imag(value)), format(cmplx.Abs(value))})
+			}
+		}
+	}
+	output.Flush()
+	return output.Error()
+}
+
+// WriteRankings writes the rankings to a csv file with a row for every node of every ranking,
+// sorted by rank within each ranking. The name column is only written when labels isn't nil and
+// the attribute columns are only written when metadata isn't nil.
+func WriteRankings(name string, labels []string, metadata *Metadata, rankings []Ranking) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	output := csv.NewWriter(file)
+	header := []string{"method", "node", "rank", "score"}
+	if labels != nil {
+		header = []string{"method", "node", "name", "rank", "score"}
+	}
+	if metadata != nil {
+		header = append(header, metadata.Columns...)
+	}
+	output.Write(header)
+	for _, ranking := range rankings {
+		for rank, node := range spectral.RankScores(ranking.Scores) {
+			score := strconv.FormatFloat(ranking.Scores[node], 'g', -1, 64)
+			row := []string{ranking.Method, strconv.Itoa(node), strconv.Itoa(rank), score}
+			if labels != nil {
+				row = []string{ranking.Method, strconv.Itoa(node), labels[node], strconv.Itoa(rank), score}
+			}
+			if metadata != nil {
+				row = append(row, metadata.Attributes(node)...)
+			}
+			output.Write(row)
+		}
+	}
+	output.Flush()
+	return output.Error()
+}
+
+// WriteSimilarity writes the node similarity matrix to a csv file in the format of -input
+func WriteSimilarity(name string, similarity *mat.Dense) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	output := csv.NewWriter(file)
+	rows, cols := similarity.Dims()
+	for i := 0; i < rows; i++ {
+		row := make([]string, cols)
+		for j := range row {
+			row[j] = strconv.FormatFloat(similarity.At(i, j), 'g', -1, 64)
+		}
+		output.Write(row)
+	}
+	output.Flush()
+	return output.Error()
+}
+
+// ProjectedNode is a node of the projection in json, the cluster and score are omitted when
+// they aren't computed
+type ProjectedNode struct {
+	Index       int       `json:"index"`
+	Label       string    `json:"label,omitempty"`
+	Coordinates []float64 `json:"coordinates"`
+	Cluster     *int      `json:"cluster,omitempty"`
+	Score       *float64  `json:"score,omitempty"`
+}
+
+// WriteProjection writes the projection onto the principal components to a json file,
+// labels, clusters and scores can be nil
+func WriteProjection(name string, proj *mat.Dense, labels []string, clusters []int, scores []float64) error {
+	rows, cols := proj.Dims()
+	nodes := make([]ProjectedNode, 0, rows)
+	for i := 0; i < rows; i++ {
+		node := ProjectedNode{Index: i, Coordinates: make([]float64, cols)}
+		for j := range node.Coordinates {
+			node.Coordinates[j] = proj.At(i, j)
+		}
+		if labels != nil {
+			node.Label = labels[i]
+		}
+		if clusters != nil {
+			node.Cluster = &clusters[i]
+		}
+		if scores != nil {
+			node.Score = &scores[i]
+		}
+		nodes = append(nodes, node)
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Nodes []ProjectedNode `json:"nodes"`
+	}{nodes})
+}
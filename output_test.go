@@ -0,0 +1,73 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+func TestWriteSimilarity(t *testing.T) {
+	half := math.Sqrt2 / 2
+	similarity := mat.NewDense(3, 3, []float64{
+		1, 0, half,
+		0, 1, -half,
+		half, -half, 1,
+	})
+	name := filepath.Join(t.TempDir(), "similarity.csv")
+	if err := WriteSimilarity(name, similarity); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "1,0,0.7071067811865476\n0,1,-0.7071067811865476\n0.7071067811865476,-0.7071067811865476,1\n"
+	if string(data) != expected {
+		t.Errorf("similarity csv\n%s\nexpected\n%s", data, expected)
+	}
+	// the similarity is in the format of -input and reads back exactly
+	loaded, err := LoadCSV(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mat.Equal(loaded, similarity) {
+		t.Errorf("loaded similarity\n%v\nexpected\n%v", mat.Formatted(loaded), mat.Formatted(similarity))
+	}
+}
+
+func TestReductionSimilarity(t *testing.T) {
+	Silence(t)
+	adjacency := KnownGraph("star")
+	graph := spectral.NewGraph(adjacency)
+	name := filepath.Join(t.TempDir(), "similarity.csv")
+	if err := Reduction(graph, ReductionOptions{Components: 2, Quiet: true, Similarity: name}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCSV(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the similarity is of the nodes projected onto the principal components
+	projection, err := graph.Project(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			a, b := projection.RawRowView(i), projection.RawRowView(j)
+			cosine := (a[0]*b[0] + a[1]*b[1]) / math.Hypot(a[0], a[1]) / math.Hypot(b[0], b[1])
+			if !(math.Abs(loaded.At(i, j)-cosine) <= 1e-12) {
+				t.Errorf("similarity of nodes %d and %d is %g, expected %g", i, j, loaded.At(i, j), cosine)
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// PipelineOptions are the options of the analysis shared by a single graph and the graphs of
+// -input-dir
+type PipelineOptions struct {
+	Directed bool
+	// Strict fails on the problems of the adjacency matrix instead of warning about them
+	Strict bool
+	// SelfLoops keeps the self-loops, they are stripped otherwise
+	SelfLoops bool
+	// Threshold prunes the edges whose weight magnitude is below it, 0 disables pruning
+	Threshold float64
+	Signed    bool
+	Normalize string
+	Laplacian bool
+	Side      string
+	Katz      bool
+	KatzAlpha float64
+	HITS      bool
+	PageRank  bool
+	Damping   float64
+	// TopK is the number of nodes printed for each ranking, 0 prints every node
+	TopK int
+}
+
+// PipelineFlags returns the pipeline options set by the flags
+func PipelineFlags() PipelineOptions {
+	return PipelineOptions{
+		Directed:  *FlagDirected,
+		Strict:    *FlagStrict,
+		SelfLoops: *FlagSelfLoops,
+		Threshold: *FlagThreshold,
+		Signed:    *FlagSigned,
+		Normalize: *FlagNormalize,
+		Laplacian: *FlagLaplacian,
+		Side:      *FlagEigenSide,
+		Katz:      *FlagKatz,
+		KatzAlpha: *FlagKatzAlpha,
+		HITS:      *FlagHITS,
+		PageRank:  *FlagPageRank,
+		Damping:   *FlagDamping,
+		TopK:      *FlagTopK,
+	}
+}
+
+// Pipeline is the analysis shared by a single graph and the graphs of -input-dir, the results
+// are written to Output, the warnings to Warnings and the eigenvalues to Debug
+type Pipeline struct {
+	PipelineOptions
+	Output   io.Writer
+	Warnings io.Writer
+	Debug    io.Writer
+}
+
+// warnf writes a warning like Logger.Warnf
+func (p *Pipeline) warnf(format string, a ...interface{}) {
+	fmt.Fprintf(p.Warnings, "warning: "+format+"\n", a...)
+}
+
+// Clean validates the adjacency matrix, then strips the self-loops unless SelfLoops is set and
+// prunes the edges below Threshold in place, returning the number of connected components. With
+// Strict the problems are returned as an error instead of written as warnings.
+func (p *Pipeline) Clean(adjacency *mat.Dense) (int, error) {
+	// without -strict self-loops are stripped rather than reported
+	problems := spectral.Validate(adjacency, !p.Directed, p.SelfLoops || !p.Strict)
+	var failures []string
+	for _, problem := range problems {
+		hint := ""
+		if errors.Is(problem, spectral.ErrAsymmetric) {
+			hint = ", use -directed for directed graphs"
+		}
+		if errors.Is(problem, spectral.ErrNegative) {
+			if p.Signed {
+				continue
+			}
+			hint = ", use -signed for signed graphs"
+		}
+		if p.Strict {
+			failures = append(failures, fmt.Sprintf("%v%s", problem, hint))
+		} else {
+			p.warnf("%v%s", problem, hint)
+		}
+	}
+	if len(failures) > 0 {
+		return 0, errors.New(strings.Join(failures, "\n"))
+	}
+	if !p.SelfLoops {
+		fmt.Fprintln(p.Output, "self-loops stripped", spectral.StripSelfLoops(adjacency))
+		fmt.Fprintf(p.Output, "\n")
+	}
+	if p.Threshold > 0 {
+		fmt.Fprintln(p.Output, "edges pruned", spectral.Threshold(adjacency, p.Threshold, !p.Directed))
+		fmt.Fprintf(p.Output, "\n")
+	}
+	components, _ := spectral.ConnectedComponents(adjacency)
+	fmt.Fprintln(p.Output, "components", components)
+	fmt.Fprintf(p.Output, "\n")
+	if components > 1 {
+		p.warnf("the graph has %d connected components, the dominant eigenvector may concentrate on one of them", components)
+	}
+	return components, nil
+}
+
+// Prepare returns the matrix that is eigendecomposed: the normalized adjacency matrix, its
+// Laplacian with Laplacian set or the signed Laplacian with Signed set, which also writes the
+// structural balance of the graph with the number of connected components
+func (p *Pipeline) Prepare(adjacency *mat.Dense, components int) (*mat.Dense, error) {
+	adjacency, err := spectral.Normalize(adjacency, p.Normalize)
+	if err != nil {
+		return nil, err
+	}
+	if p.Laplacian {
+		adjacency = spectral.Laplacian(adjacency)
+	}
+	if p.Signed {
+		balance, err := spectral.StructuralBalance(adjacency)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(p.Output, "smallest signed laplacian eigenvalue", balance.Smallest)
+		fmt.Fprintln(p.Output, "balanced components", balance.Balanced, "of", components)
+		if balance.Balanced == components {
+			fmt.Fprintln(p.Output, "the graph is structurally balanced, the factions are")
+			for i, faction := range balance.Factions {
+				fmt.Fprintln(p.Output, i, faction)
+			}
+		} else {
+			fmt.Fprintln(p.Output, "the graph isn't structurally balanced")
+		}
+		fmt.Fprintf(p.Output, "\n")
+		adjacency = spectral.SignedLaplacian(adjacency)
+	}
+	return adjacency, nil
+}
+
+// Graph returns the graph of the prepared matrix with the eigenvectors of Side
+func (p *Pipeline) Graph(adjacency *mat.Dense) *spectral.Graph {
+	graph := spectral.NewGraph(adjacency)
+	graph.Side = p.Side
+	return graph
+}
+
+// Summarize writes each eigenvalue with its magnitude, phase and participation ratio to Debug
+// and the spectral gap, the participation ratio of the dominant eigenvector and with Laplacian
+// the connectivity to Output
+func (p *Pipeline) Summarize(spectrum *spectral.Spectrum) {
+	values := spectrum.Values
+	ratios := spectral.ParticipationRatios(spectrum.Vectors)
+	for i, value := range values {
+		fmt.Fprintln(p.Debug, i, value, cmplx.Abs(value), cmplx.Phase(value), ratios[i])
+	}
+	fmt.Fprintf(p.Debug, "\n")
+	fmt.Fprintln(p.Output, "spectral gap", spectral.SpectralGap(values))
+	fmt.Fprintln(p.Output, "participation ratio", ratios[spectrum.Dominant], "of", len(values))
+	if p.Laplacian {
+		fmt.Fprintln(p.Output, "connected components", spectral.ZeroEigenvalues(values, 1e-9))
+		fmt.Fprintln(p.Output, "algebraic connectivity", spectral.AlgebraicConnectivity(values))
+	}
+	fmt.Fprintf(p.Output, "\n")
+}
+
+// Rankings are the scores of the nodes computed by Pipeline.Rank, Katz and PageRank are nil
+// unless they are enabled
+type Rankings struct {
+	// Magnitudes are the magnitudes of the real parts of the dominant eigenvector
+	Magnitudes []float64
+	Centrality []float64
+	Katz       []float64
+	PageRank   []float64
+	// List is the spectral ranking followed by katz and page rank when they are enabled, in the
+	// order of -ranking-output
+	List []Ranking
+}
+
+// Rank ranks the nodes of the graph by the dominant eigenvector, eigenvector centrality and the
+// enabled rankings: katz, hits and page rank. It writes the top nodes of each ranking to Output.
+func (p *Pipeline) Rank(graph *spectral.Graph, spectrum *spectral.Spectrum) (*Rankings, error) {
+	ranking, err := graph.Rank()
+	if err != nil {
+		return nil, err
+	}
+	adjacency, vectors, dominant := graph.Adjacency, spectrum.Vectors, spectrum.Dominant
+	rankings := &Rankings{Magnitudes: make([]float64, len(spectrum.Values))}
+	for node := range rankings.Magnitudes {
+		rankings.Magnitudes[node] = math.Abs(real(vectors.At(node, dominant)))
+	}
+	for i, node := range TopK(ranking, p.TopK) {
+		fmt.Fprintln(p.Output, i, node, rankings.Magnitudes[node])
+	}
+	rankings.List = []Ranking{{Method: "spectral", Scores: rankings.Magnitudes}}
+
+	rankings.Centrality = spectral.EigenvectorCentrality(spectrum)
+	fmt.Fprintf(p.Output, "\n")
+	fmt.Fprintln(p.Output, "eigenvector centrality")
+	for i, node := range TopK(spectral.RankScores(rankings.Centrality), p.TopK) {
+		fmt.Fprintln(p.Output, i, node, rankings.Centrality[node])
+	}
+
+	if p.Katz {
+		if limit := 1 / cmplx.Abs(spectrum.Values[dominant]); p.KatzAlpha >= limit {
+			p.warnf("katz alpha %g is not below 1/|λ| = %g, the centrality doesn't converge", p.KatzAlpha, limit)
+		}
+		rankings.Katz, err = spectral.Katz(adjacency, p.KatzAlpha, 1)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(p.Output, "\n")
+		fmt.Fprintln(p.Output, "katz centrality")
+		for i, node := range TopK(spectral.RankScores(rankings.Katz), p.TopK) {
+			fmt.Fprintln(p.Output, i, node, rankings.Katz[node])
+		}
+		rankings.List = append(rankings.List, Ranking{Method: "katz", Scores: rankings.Katz})
+	}
+
+	if p.HITS {
+		hubs, authorities := spectral.HITS(adjacency, 1e-12, 1000)
+		fmt.Fprintf(p.Output, "\n")
+		fmt.Fprintln(p.Output, "hubs")
+		for i, node := range TopK(spectral.RankScores(hubs), p.TopK) {
+			fmt.Fprintln(p.Output, i, node, hubs[node])
+		}
+		fmt.Fprintf(p.Output, "\n")
+		fmt.Fprintln(p.Output, "authorities")
+		for i, node := range TopK(spectral.RankScores(authorities), p.TopK) {
+			fmt.Fprintln(p.Output, i, node, authorities[node])
+		}
+	}
+
+	if p.PageRank {
+		rankings.PageRank = spectral.PageRank(adjacency, p.Damping, 1e-12, 1000)
+		fmt.Fprintf(p.Output, "\n")
+		for i, node := range TopK(spectral.RankScores(rankings.PageRank), p.TopK) {
+			fmt.Fprintln(p.Output, i, node, rankings.PageRank[node])
+		}
+		rankings.List = append(rankings.List, Ranking{Method: "pagerank", Scores: rankings.PageRank})
+	}
+	return rankings, nil
+}
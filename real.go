@@ -0,0 +1,288 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/pointlander/gradient/tf64"
+	"gonum.org/v1/gonum/mat"
+)
+
+// NeuralReal is neural mode with real weights, the graph must be symmetric so that
+// the eigenvalues and eigenvectors are real
+func NeuralReal(ctx context.Context, rng *rand.Rand, size int, vectors *mat.CDense, values []complex128, options NeuralOptions) error {
+	if err := CheckLoss(options.Loss, vectors, values); err != nil {
+		return err
+	}
+	network, err := NewRealNetwork(rng, size, vectors, values, options)
+	if err != nil {
+		return err
+	}
+	return RunNetwork(ctx, network, size, options)
+}
+
+// RealNetwork is the cost graph of neural mode with real weights
+type RealNetwork struct {
+	set, best  tf64.Set
+	cost, full tf64.Meta
+	validate   func() float64
+	sample     func()
+	initialize func()
+	options    NeuralOptions
+	// adam first and second moment estimates and sgd velocities for momentum
+	m, v, velocity [][]float64
+}
+
+// NewRealNetwork builds the cost graph of neural mode with real weights for the eigenpairs
+func NewRealNetwork(rng *rand.Rand, size int, vectors *mat.CDense, values []complex128, options NeuralOptions) (*RealNetwork, error) {
+	n := &RealNetwork{set: tf64.NewSet(), options: options}
+	set := &n.set
+	for l := 0; l < options.Layers; l++ {
+		set.Add(LayerName(l), size, size)
+	}
+	train, held, err := Split(rng, size, options.ValSplit)
+	if err != nil {
+		return nil, err
+	}
+	// the eigenpairs are inputs, so they are kept out of the trained set
+	inputs := tf64.NewSet()
+	inputs.Add("X", size, 2*len(train))
+	inputs.Add("Y", size, 2*len(train))
+	inputs.Add("VX", size, 2*len(held))
+	inputs.Add("VY", size, 2*len(held))
+
+	warm := Init(options.Init, size, options.Adjacency, values)
+	n.initialize = func() {
+		for _, w := range set.Weights {
+			w.X = w.X[:0]
+			for i := 0; i < cap(w.X); i++ {
+				w.X = append(w.X, 2*rng.Float64()-1)
+			}
+		}
+		copy(set.Weights[0].X, warm)
+	}
+	n.initialize()
+
+	pairs := func(x, y *tf64.V, columns []int) {
+		inputs, targets := Eigenpairs(vectors, values, columns)
+		x.X, y.X = append(x.X, inputs...), append(y.X, targets...)
+	}
+	x, y := inputs.Weights[0], inputs.Weights[1]
+	pairs(x, y, train)
+	pairs(inputs.Weights[2], inputs.Weights[3], held)
+
+	input, output := inputs.Get("X"), inputs.Get("Y")
+	if options.Batch > 0 && options.Batch < len(train) {
+		batch := tf64.NewSet()
+		batch.Add("X", size, 2*options.Batch)
+		batch.Add("Y", size, 2*options.Batch)
+		bx, by := batch.Weights[0], batch.Weights[1]
+		bx.X, by.X = bx.X[:cap(bx.X)], by.X[:cap(by.X)]
+		columns := Columns(rng, len(train), options.Batch)
+		n.sample = func() {
+			for b, k := range columns() {
+				copy(bx.X[2*b*size:2*(b+1)*size], x.X[2*k*size:2*(k+1)*size])
+				copy(by.X[2*b*size:2*(b+1)*size], y.X[2*k*size:2*(k+1)*size])
+			}
+		}
+		input, output = batch.Get("X"), batch.Get("Y")
+	}
+
+	network := func(input tf64.Meta) tf64.Meta {
+		l1 := tf64.Mul(set.Get("A"), input)
+		for l := 1; l < options.Layers; l++ {
+			l1 = tf64.Mul(set.Get(LayerName(l)), tf64.TanH(l1))
+		}
+		return l1
+	}
+	constants := tf64.NewSet()
+	constants.Add("L2", 1, 1)
+	constants.Weights[0].X = append(constants.Weights[0].X, options.L2)
+	regularize := func(cost tf64.Meta) tf64.Meta {
+		if options.L2 > 0 {
+			for _, w := range set.Weights {
+				a := set.Get(w.N)
+				cost = tf64.Add(cost, tf64.Hadamard(constants.Get("L2"), tf64.Sum(tf64.Hadamard(a, a))))
+			}
+		}
+		return cost
+	}
+	n.cost = regularize(LossReal(options.Loss, network(input), output))
+	n.full = n.cost
+	if n.sample != nil {
+		n.full = regularize(LossReal(options.Loss, network(inputs.Get("X")), inputs.Get("Y")))
+	}
+	if len(held) > 0 {
+		validation := LossReal(options.Loss, network(inputs.Get("VX")), inputs.Get("VY"))
+		n.validate = func() float64 {
+			value := 0.0
+			validation(func(a *tf64.V) bool {
+				value = math.Abs(a.X[0])
+				return true
+			})
+			return value
+		}
+	}
+	return n, nil
+}
+
+// Initialize sets the weights to new random values
+func (n *RealNetwork) Initialize() {
+	n.initialize()
+}
+
+// Cost evaluates the cost over every training eigenvector
+func (n *RealNetwork) Cost() float64 {
+	cost := 0.0
+	n.full(func(a *tf64.V) bool {
+		cost = math.Abs(a.X[0])
+		return true
+	})
+	return cost
+}
+
+// Reset clears the optimizer state
+func (n *RealNetwork) Reset() {
+	n.m, n.v = make([][]float64, len(n.set.Weights)), make([][]float64, len(n.set.Weights))
+	n.velocity = make([][]float64, len(n.set.Weights))
+	for l, w := range n.set.Weights {
+		n.m[l], n.v[l] = make([]float64, len(w.X)), make([]float64, len(w.X))
+		n.velocity[l] = make([]float64, len(w.X))
+	}
+}
+
+// Gradient computes the gradient of the cost
+func (n *RealNetwork) Gradient() (cost, data, regularization, norm float64) {
+	if n.sample != nil {
+		n.sample()
+	}
+	n.set.Zero()
+
+	total := tf64.Gradient(n.cost).X[0]
+	penalty := 0.0
+	for _, w := range n.set.Weights {
+		for _, a := range w.X {
+			penalty += a * a
+		}
+	}
+	penalty *= n.options.L2
+	sum := 0.0
+	for _, p := range n.set.Weights {
+		for _, d := range p.D {
+			sum += d * d
+		}
+	}
+	return math.Abs(total), math.Abs(total - penalty), math.Abs(penalty), math.Sqrt(sum)
+}
+
+// Update applies the gradient with the optimizer
+func (n *RealNetwork) Update(i int, scaling float64) {
+	eta, beta1, beta2, epsilon := n.options.Eta, n.options.Beta1, n.options.Beta2, n.options.Epsilon
+	for l, w := range n.set.Weights {
+		switch n.options.Optimizer {
+		case "adam":
+			b1, b2 := 1-math.Pow(beta1, float64(i+1)), 1-math.Pow(beta2, float64(i+1))
+			m, v := n.m[l], n.v[l]
+			for j, d := range w.D {
+				g := d * scaling
+				m[j] = beta1*m[j] + (1-beta1)*g
+				v[j] = beta2*v[j] + (1-beta2)*g*g
+				w.X[j] -= eta * (m[j] / b1) / (math.Sqrt(v[j]/b2) + epsilon)
+			}
+		default:
+			velocity := n.velocity[l]
+			for j, d := range w.D {
+				velocity[j] = n.options.Momentum*velocity[j] - eta*d*scaling
+				w.X[j] += velocity[j]
+			}
+		}
+	}
+}
+
+// Validation returns the cost on the held out eigenvectors
+func (n *RealNetwork) Validation() func() float64 {
+	return n.validate
+}
+
+// Keep records the current weights as the best
+func (n *RealNetwork) Keep() {
+	n.best = n.set.Copy()
+}
+
+// Restore sets the weights to the best weights
+func (n *RealNetwork) Restore() {
+	for i, w := range n.best.Weights {
+		copy(n.set.Weights[i].X, w.X)
+	}
+}
+
+// Save saves the weights
+func (n *RealNetwork) Save(name string, cost float64, epochs int) error {
+	return n.set.Save(name, cost, epochs)
+}
+
+// Load loads the weights
+func (n *RealNetwork) Load(name string) error {
+	return LoadWeightsReal(name, &n.set)
+}
+
+// Weights returns the names and the weights of the layers as complex numbers
+func (n *RealNetwork) Weights() ([]string, [][]complex128) {
+	layers, weights := make([]string, 0, len(n.set.Weights)), make([][]complex128, 0, len(n.set.Weights))
+	for _, w := range n.set.Weights {
+		weight := make([]complex128, len(w.X))
+		for i, value := range w.X {
+			weight[i] = complex(value, 0)
+		}
+		layers, weights = append(layers, w.N), append(weights, weight)
+	}
+	return layers, weights
+}
+
+// FullGradient returns the gradient of the first layer over every training eigenvector
+func (n *RealNetwork) FullGradient() []complex128 {
+	n.set.Zero()
+	tf64.Gradient(n.full)
+	w := n.set.Weights[0]
+	gradient := make([]complex128, len(w.D))
+	for i, d := range w.D {
+		gradient[i] = complex(d, 0)
+	}
+	return gradient
+}
+
+// LossReal is the named loss between the real outputs of the network and the targets
+func LossReal(name string, outputs, targets tf64.Meta) tf64.Meta {
+	switch name {
+	case "crossentropy":
+		return tf64.Sum(tf64.CrossEntropy(outputs, targets))
+	}
+	return tf64.Sum(tf64.Quadratic(targets, outputs))
+}
+
+// LoadWeightsReal loads previously trained real weights into every weight in the set
+func LoadWeightsReal(name string, set *tf64.Set) error {
+	loaded := tf64.NewSet()
+	_, _, err := loaded.Open(name)
+	if err != nil {
+		return err
+	}
+	for _, w := range set.Weights {
+		layer := w.N
+		a, ok := loaded.ByName[layer]
+		if !ok {
+			return fmt.Errorf("%s: no %s weights", name, layer)
+		}
+		if len(a.S) != 2 || a.S[0] != w.S[0] || a.S[1] != w.S[1] {
+			return fmt.Errorf("%s: %s weights are %v, expected %dx%d", name, layer, a.S, w.S[0], w.S[1])
+		}
+		w.Set(a.X)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/cmplx"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// ReferenceTolerance is how far an eigenvalue can be from its reference value
+const ReferenceTolerance = 1e-9
+
+// ReferenceCase is an adjacency matrix and its reference eigenvalues in json
+type ReferenceCase struct {
+	Name      string      `json:"name"`
+	Adjacency [][]float64 `json:"adjacency"`
+	Values    []Complex   `json:"values"`
+}
+
+// SortReference sorts eigenvalues by descending magnitude, eigenvalues with the same magnitude
+// within ReferenceTolerance are sorted by real and then imaginary part so that the order
+// doesn't depend on the factorization
+func SortReference(values []complex128) {
+	key := func(v float64) float64 {
+		return math.Round(v / ReferenceTolerance)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		a, b := values[i], values[j]
+		if ka, kb := key(cmplx.Abs(a)), key(cmplx.Abs(b)); ka != kb {
+			return ka > kb
+		}
+		if ka, kb := key(real(a)), key(real(b)); ka != kb {
+			return ka > kb
+		}
+		return key(imag(a)) > key(imag(b))
+	})
+}
+
+// CheckReference decomposes the adjacency matrix of the case and compares the eigenvalues
+// against the reference values
+func CheckReference(reference ReferenceCase) error {
+	size := len(reference.Adjacency)
+	if size == 0 {
+		return fmt.Errorf("no adjacency matrix")
+	}
+	adjacency := mat.NewDense(size, size, nil)
+	for i, row := range reference.Adjacency {
+		if len(row) != size {
+			return fmt.Errorf("row %d has %d entries, expected %d", i, len(row), size)
+		}
+		adjacency.SetRow(i, row)
+	}
+	spectrum, err := spectral.Decompose(adjacency, spectral.DecomposeOptions{})
+	if err != nil {
+		return err
+	}
+	values := spectrum.Values
+	if len(values) != len(reference.Values) {
+		return fmt.Errorf("%d eigenvalues, expected %d", len(values), len(reference.Values))
+	}
+
+	expected := make([]complex128, len(reference.Values))
+	for i, value := range reference.Values {
+		expected[i] = complex(value.Real, value.Imag)
+	}
+	actual := append([]complex128(nil), values...)
+	SortReference(expected)
+	SortReference(actual)
+	for i := range expected {
+		if cmplx.Abs(actual[i]-expected[i]) > ReferenceTolerance {
+			return fmt.Errorf("eigenvalue %d is %v, expected %v", i, actual[i], expected[i])
+		}
+	}
+	return nil
+}
+
+// TestReference checks the eigendecomposition against every json reference case in testdata/eigen
+func TestReference(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("testdata", "eigen", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("testdata/eigen: no reference cases")
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reference ReferenceCase
+		if err := json.Unmarshal(data, &reference); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if err := CheckReference(reference); err != nil {
+			t.Errorf("%s (%s): %v", name, reference.Name, err)
+		}
+	}
+}
+
+// TestSortReference checks that eigenvalues within ReferenceTolerance of each other are ordered
+// by their rounded parts whichever order they start in
+func TestSortReference(t *testing.T) {
+	expected := []complex128{complex(0, 1), complex(0, -1), complex(-1, 0), complex(.5, 0)}
+	cases := [][]complex128{
+		{complex(0, -1), complex(.5, 0), complex(0, 1), complex(-1, 0)},
+		{complex(.5, 0), complex(-1, 0), complex(0, -1), complex(0, 1)},
+		// the magnitudes differ by less than the tolerance, so the real parts decide
+		{complex(0, 1), complex(-1+1e-11, 0), complex(0, -1), complex(.5, 0)},
+	}
+	for i, values := range cases {
+		SortReference(values)
+		for j := range values {
+			if cmplx.Abs(values[j]-expected[j]) > ReferenceTolerance {
+				t.Errorf("case %d: eigenvalue %d is %v, expected %v", i, j, values[j], expected[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"errors"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Bipartite 2-colors the graph with a breadth first search, ignoring the direction of the
+// edges. It returns the color of each node, 0 for the left partition and 1 for the right, and
+// false if an odd cycle or a self-loop makes the graph not bipartite. The first node of every
+// connected component is on the left.
+func Bipartite(a *mat.Dense) ([]int, bool) {
+	size, _ := a.Dims()
+	color := make([]int, size)
+	for i := range color {
+		color[i] = -1
+	}
+	for start := 0; start < size; start++ {
+		if color[start] != -1 {
+			continue
+		}
+		color[start] = 0
+		queue := []int{start}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			for next := 0; next < size; next++ {
+				if a.At(node, next) == 0 && a.At(next, node) == 0 {
+					continue
+				}
+				if color[next] == color[node] {
+					return nil, false
+				}
+				if color[next] == -1 {
+					color[next] = 1 - color[node]
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return color, true
+}
+
+// BipartiteRanking ranks the two partitions of a bipartite graph by the leading singular
+// vectors of the biadjacency matrix
+type BipartiteRanking struct {
+	// Value is the largest singular value
+	Value float64
+	// Left and Right are the nodes of the partitions in order
+	Left, Right []int
+	// LeftScores and RightScores are the magnitudes of the entries of the left and right
+	// singular vectors, LeftScores[k] is the score of node Left[k]
+	LeftScores, RightScores []float64
+}
+
+// SVDRank computes the singular value decomposition of the biadjacency matrix B, the block of the
+// symmetrized adjacency matrix with a row for each node of the left partition and a column for
+// each node of the right partition. The left singular vectors are the eigenvectors of B Bᵀ, which
+// links the left nodes through their shared right neighbors, and the right ones those of Bᵀ B.
+func SVDRank(a *mat.Dense, color []int) (*BipartiteRanking, error) {
+	ranking := &BipartiteRanking{}
+	for node, c := range color {
+		if c == 0 {
+			ranking.Left = append(ranking.Left, node)
+		} else {
+			ranking.Right = append(ranking.Right, node)
+		}
+	}
+	if len(ranking.Left) == 0 || len(ranking.Right) == 0 {
+		return nil, errors.New("a partition of the bipartite graph is empty, the graph has no edges")
+	}
+	b := mat.NewDense(len(ranking.Left), len(ranking.Right), nil)
+	for i, l := range ranking.Left {
+		for j, r := range ranking.Right {
+			b.Set(i, j, (a.At(l, r)+a.At(r, l))/2)
+		}
+	}
+	var svd mat.SVD
+	if !svd.Factorize(b, mat.SVDThin) {
+		return nil, errors.New("the singular value decomposition of the biadjacency matrix failed")
+	}
+	// the singular values are in descending order
+	ranking.Value = svd.Values(nil)[0]
+	var u, v mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+	ranking.LeftScores = make([]float64, len(ranking.Left))
+	for i := range ranking.LeftScores {
+		ranking.LeftScores[i] = math.Abs(u.At(i, 0))
+	}
+	ranking.RightScores = make([]float64, len(ranking.Right))
+	for j := range ranking.RightScores {
+		ranking.RightScores[j] = math.Abs(v.At(j, 0))
+	}
+	return ranking, nil
+}
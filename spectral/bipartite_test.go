@@ -0,0 +1,96 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestBipartite(t *testing.T) {
+	looped := mat.DenseCopyOf(path)
+	looped.Set(2, 2, 1)
+	cases := []struct {
+		name      string
+		a         *mat.Dense
+		color     []int
+		bipartite bool
+	}{
+		{"path", path, []int{0, 1, 0}, true},
+		{"star", star, []int{0, 1, 1, 1}, true},
+		{"even cycle", undirected(4, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}, [2]int{3, 0}), []int{0, 1, 0, 1}, true},
+		// the first node of every component is on the left
+		{"two edges", undirected(4, [2]int{0, 3}, [2]int{1, 2}), []int{0, 0, 1, 1}, true},
+		{"directed", dense(
+			[]float64{0, 0, 0},
+			[]float64{0, 0, 0},
+			[]float64{1, 1, 0},
+		), []int{0, 0, 1}, true},
+		{"triangle", triangle, nil, false},
+		{"odd cycle", undirected(5, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}, [2]int{3, 4}, [2]int{4, 0}), nil, false},
+		{"self-loop", looped, nil, false},
+	}
+	for _, c := range cases {
+		color, bipartite := Bipartite(c.a)
+		if bipartite != c.bipartite || !reflect.DeepEqual(color, c.color) {
+			t.Errorf("%s: colors %v bipartite %t, expected %v bipartite %t", c.name, color, bipartite, c.color, c.bipartite)
+		}
+	}
+}
+
+func TestSVDRank(t *testing.T) {
+	third := 1 / math.Sqrt(3)
+	// the biadjacency matrix [[1 1] [0 1]] has the singular value φ, with the singular vectors
+	// (φ, 1) and (1, φ) like the hubs and authorities of the same graph
+	phi := (1 + math.Sqrt(5)) / 2
+	norm := math.Sqrt(1 + phi*phi)
+	cases := []struct {
+		name                    string
+		a                       *mat.Dense
+		value                   float64
+		left, right             []int
+		leftScores, rightScores []float64
+	}{
+		{"star", star, math.Sqrt(3), []int{0}, []int{1, 2, 3}, []float64{1}, []float64{third, third, third}},
+		{"path", undirected(4, [2]int{0, 2}, [2]int{0, 3}, [2]int{1, 3}), phi, []int{0, 1}, []int{2, 3},
+			[]float64{phi / norm, 1 / norm}, []float64{1 / norm, phi / norm}},
+		// a directed edge has half the weight of an undirected one
+		{"directed", dense([]float64{0, 2}, []float64{0, 0}), 1, []int{0}, []int{1}, []float64{1}, []float64{1}},
+	}
+	for _, c := range cases {
+		color, ok := Bipartite(c.a)
+		if !ok {
+			t.Fatalf("%s isn't bipartite", c.name)
+		}
+		ranking, err := SVDRank(c.a, color)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(ranking.Value-c.value) > 1e-9 {
+			t.Errorf("%s: singular value %g, expected %g", c.name, ranking.Value, c.value)
+		}
+		if !reflect.DeepEqual(ranking.Left, c.left) || !reflect.DeepEqual(ranking.Right, c.right) {
+			t.Errorf("%s: partitions %v and %v, expected %v and %v", c.name, ranking.Left, ranking.Right, c.left, c.right)
+		}
+		for i, score := range ranking.LeftScores {
+			if math.Abs(score-c.leftScores[i]) > 1e-9 {
+				t.Errorf("%s: left scores %v, expected %v", c.name, ranking.LeftScores, c.leftScores)
+				break
+			}
+		}
+		for i, score := range ranking.RightScores {
+			if math.Abs(score-c.rightScores[i]) > 1e-9 {
+				t.Errorf("%s: right scores %v, expected %v", c.name, ranking.RightScores, c.rightScores)
+				break
+			}
+		}
+	}
+	if _, err := SVDRank(mat.NewDense(3, 3, nil), []int{0, 0, 0}); err == nil {
+		t.Errorf("svd ranking without edges: no error")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Perron returns the index of the real eigenvalue with the largest value, which for a
+// non-negative matrix is the Perron-Frobenius eigenvalue. Dominant can pick -λ instead
+// when the graph is bipartite.
+func Perron(values []complex128) int {
+	index, max := -1, math.Inf(-1)
+	for i, value := range values {
+		if math.Abs(imag(value)) <= Tolerance && real(value) > max {
+			index, max = i, real(value)
+		}
+	}
+	if index == -1 {
+		return Dominant(values)
+	}
+	return index
+}
+
+// EigenvectorCentrality returns the eigenvector centrality of each node, the Perron eigenvector
+// with its sign fixed so that the entries are positive and scaled to unit length
+func EigenvectorCentrality(spectrum *Spectrum) []float64 {
+	perron := Perron(spectrum.Values)
+	vectors := spectrum.Vectors
+	size, _ := vectors.Dims()
+	centrality := make([]float64, size)
+	sum, norm := 0.0, 0.0
+	for i := range centrality {
+		centrality[i] = real(vectors.At(i, perron))
+		sum += centrality[i]
+		norm += centrality[i] * centrality[i]
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return centrality
+	}
+	if sum < 0 {
+		norm = -norm
+	}
+	for i := range centrality {
+		centrality[i] /= norm
+		// entries that are zero up to rounding can come out with the wrong sign
+		if centrality[i] < 0 && centrality[i] > -Tolerance {
+			centrality[i] = 0
+		}
+	}
+	return centrality
+}
+
+// Katz returns the Katz centrality of each node, the solution of (I - alpha A) x = beta 1,
+// the series only converges when alpha is below 1/|λ| for the dominant eigenvalue λ
+func Katz(a *mat.Dense, alpha, beta float64) ([]float64, error) {
+	size, _ := a.Dims()
+	system := mat.NewDense(size, size, nil)
+	system.Scale(-alpha, a)
+	for i := 0; i < size; i++ {
+		system.Set(i, i, 1+system.At(i, i))
+	}
+	b := mat.NewVecDense(size, nil)
+	for i := 0; i < size; i++ {
+		b.SetVec(i, beta)
+	}
+	var x mat.VecDense
+	err := x.SolveVec(system, b)
+	if err != nil {
+		return nil, fmt.Errorf("katz centrality with alpha %g: %v", alpha, err)
+	}
+	return x.RawVector().Data, nil
+}
+
+// ParticipationRatios returns the participation ratio (Σ|v_i|²)² / Σ|v_i|⁴ of each eigenvector,
+// the number of nodes it is spread across: N for a uniform vector and 1 for a single node.
+// A zero vector has a ratio of 0.
+func ParticipationRatios(vectors *mat.CDense) []float64 {
+	rows, cols := vectors.Dims()
+	ratios := make([]float64, cols)
+	for k := range ratios {
+		squares, fourths := 0.0, 0.0
+		for i := 0; i < rows; i++ {
+			value := vectors.At(i, k)
+			square := real(value)*real(value) + imag(value)*imag(value)
+			squares += square
+			fourths += square * square
+		}
+		if fourths > 0 {
+			ratios[k] = squares * squares / fourths
+		}
+	}
+	return ratios
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPerron(t *testing.T) {
+	cases := []struct {
+		values []complex128
+		perron int
+	}{
+		{[]complex128{-3, 2, 1}, 1},
+		{[]complex128{1 + 2i, 1 - 2i, -1}, 2},
+		// without a real eigenvalue it falls back to the dominant one
+		{[]complex128{1 + 1i, 2 - 2i}, 1},
+	}
+	for _, c := range cases {
+		if perron := Perron(c.values); perron != c.perron {
+			t.Errorf("%v: perron %d, expected %d", c.values, perron, c.perron)
+		}
+	}
+}
+
+func TestEigenvectorCentrality(t *testing.T) {
+	third := 1 / math.Sqrt(3)
+	leaf := 1 / math.Sqrt(6)
+	cases := []struct {
+		name       string
+		a          *mat.Dense
+		centrality []float64
+	}{
+		{"triangle", triangle, []float64{third, third, third}},
+		// the star and the path are bipartite, the centrality is the eigenvector of λ and not -λ
+		{"star", star, []float64{math.Sqrt2 / 2, leaf, leaf, leaf}},
+		{"path", path, []float64{.5, math.Sqrt2 / 2, .5}},
+		{"cycle", cycle, []float64{third, third, third}},
+	}
+	for _, c := range cases {
+		spectrum, err := Decompose(c.a, DecomposeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		centrality := EigenvectorCentrality(spectrum)
+		for i, v := range centrality {
+			if math.Abs(v-c.centrality[i]) > 1e-9 {
+				t.Errorf("%s: centrality %v, expected %v", c.name, centrality, c.centrality)
+				break
+			}
+		}
+	}
+}
+
+func TestKatz(t *testing.T) {
+	const alpha, beta = .1, 2
+	// x_i = beta + alpha Σ_j a_ij x_j, so the center of the star has x0 = beta + 3 alpha xl
+	// with xl = beta + alpha x0
+	center := beta * (1 + 3*alpha) / (1 - 3*alpha*alpha)
+	cases := []struct {
+		name  string
+		a     *mat.Dense
+		alpha float64
+		katz  []float64
+	}{
+		{"triangle", triangle, alpha, []float64{beta / (1 - 2*alpha), beta / (1 - 2*alpha), beta / (1 - 2*alpha)}},
+		{"star", star, alpha, []float64{center, beta + alpha*center, beta + alpha*center, beta + alpha*center}},
+		// the walks of a directed path only go forward
+		{"directed path", dense(
+			[]float64{0, 1, 0},
+			[]float64{0, 0, 1},
+			[]float64{0, 0, 0},
+		), alpha, []float64{beta * (1 + alpha + alpha*alpha), beta * (1 + alpha), beta}},
+		{"no attenuation", triangle, 0, []float64{beta, beta, beta}},
+	}
+	for _, c := range cases {
+		katz, err := Katz(c.a, c.alpha, beta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range katz {
+			if math.Abs(v-c.katz[i]) > 1e-9 {
+				t.Errorf("%s: katz %v, expected %v", c.name, katz, c.katz)
+				break
+			}
+		}
+	}
+	// I - A/2 is singular for the triangle, whose dominant eigenvalue is 2
+	if katz, err := Katz(triangle, .5, 1); err == nil {
+		t.Errorf("alpha 1/λ: katz %v without an error", katz)
+	}
+}
+
+func TestParticipationRatios(t *testing.T) {
+	// each column is a case, the ratio doesn't depend on the scale or the phase of the vector
+	vectors := mat.NewCDense(4, 6, []complex128{
+		.5, 1, 1, 1i, 2, 0,
+		.5, 0, 1, 1, 2, 0,
+		.5, 0, 0, 0, 0, 0,
+		.5, 0, 0, 0, 1, 0,
+	})
+	// the fifth column is (2, 2, 0, 1) with Σ|v|² = 9 and Σ|v|⁴ = 33
+	expected := []float64{4, 1, 2, 2, 81. / 33, 0}
+	ratios := ParticipationRatios(vectors)
+	for k, ratio := range ratios {
+		if math.Abs(ratio-expected[k]) > 1e-12 {
+			t.Errorf("column %d: participation ratio %g, expected %g", k, ratio, expected[k])
+		}
+	}
+
+	// the perron eigenvector of the star is (1/√2, 1/√6, 1/√6, 1/√6) with Σ|v|⁴ = 1/3
+	spectrum, err := Decompose(star, DecomposeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ratio := ParticipationRatios(spectrum.Vectors)[Perron(spectrum.Values)]; math.Abs(ratio-3) > 1e-9 {
+		t.Errorf("the perron eigenvector of the star has the participation ratio %g, expected 3", ratio)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DecomposeComplex computes the right eigendecomposition of a complex matrix M = A + iB. Gonum
+// only factorizes real matrices, so the real 2n×2n matrix R = [A -B; B A] is factorized instead:
+// for every eigenpair M v = λ v the vector [v; -iv] is an eigenvector of R with eigenvalue λ and
+// the other n eigenpairs of R belong to the conjugate of M. The eigenvector [x; y] of R is
+// mapped back to (x + iy)/2, which vanishes for the eigenpairs of the conjugate, and the n
+// eigenpairs with the largest such vectors are kept.
+func DecomposeComplex(m *mat.CDense) (*Spectrum, error) {
+	size, cols := m.Dims()
+	if size != cols {
+		return nil, fmt.Errorf("%w: %d×%d matrix isn't square", ErrEigen, size, cols)
+	}
+	r := mat.NewDense(2*size, 2*size, nil)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			value := m.At(i, j)
+			if cmplx.IsNaN(value) || cmplx.IsInf(value) {
+				return nil, fmt.Errorf("%w: non-finite entry at (%d, %d)", ErrEigen, i, j)
+			}
+			r.Set(i, j, real(value))
+			r.Set(i, j+size, -imag(value))
+			r.Set(i+size, j, imag(value))
+			r.Set(i+size, j+size, real(value))
+		}
+	}
+	var eig mat.Eigen
+	if !eig.Factorize(r, mat.EigenRight) {
+		return nil, ErrEigen
+	}
+	values := eig.Values(nil)
+	var embedded mat.CDense
+	eig.VectorsTo(&embedded)
+
+	type candidate struct {
+		value  complex128
+		vector []complex128
+		norm   float64
+	}
+	candidates := make([]candidate, len(values))
+	for k, value := range values {
+		vector, norm, total := make([]complex128, size), 0.0, 0.0
+		for i := range vector {
+			x, y := embedded.At(i, k), embedded.At(i+size, k)
+			vector[i] = (x + 1i*y) / 2
+			norm += real(vector[i] * cmplx.Conj(vector[i]))
+			total += real(x*cmplx.Conj(x)) + real(y*cmplx.Conj(y))
+		}
+		if total > 0 {
+			norm /= total
+		}
+		candidates[k] = candidate{value: value, vector: vector, norm: norm}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].norm > candidates[j].norm
+	})
+
+	// an eigenvalue shared by M and its conjugate has a 2d eigenspace in R, so a candidate
+	// is only kept after removing the parts along the kept vectors with the same eigenvalue
+	kept := make([]candidate, 0, size)
+	for _, c := range candidates {
+		if len(kept) == size {
+			break
+		}
+		vector := append([]complex128(nil), c.vector...)
+		for _, k := range kept {
+			if cmplx.Abs(k.value-c.value) > math.Sqrt(Tolerance) {
+				continue
+			}
+			var dot complex128
+			for i := range vector {
+				dot += cmplx.Conj(k.vector[i]) * vector[i]
+			}
+			for i := range vector {
+				vector[i] -= dot * k.vector[i]
+			}
+		}
+		norm := 0.0
+		for _, v := range vector {
+			norm += real(v * cmplx.Conj(v))
+		}
+		norm = math.Sqrt(norm)
+		if norm < math.Sqrt(Tolerance) {
+			continue
+		}
+		for i := range vector {
+			vector[i] /= complex(norm, 0)
+		}
+		kept = append(kept, candidate{value: c.value, vector: vector, norm: c.norm})
+	}
+	if len(kept) != size {
+		return nil, fmt.Errorf("%w: found %d of %d eigenvectors", ErrEigen, len(kept), size)
+	}
+
+	spectrum := &Spectrum{Values: make([]complex128, size), Vectors: mat.NewCDense(size, size, nil)}
+	for j, k := range kept {
+		spectrum.Values[j] = k.value
+		for i, v := range k.vector {
+			spectrum.Vectors.Set(i, j, v)
+		}
+	}
+	spectrum.Values = SortEigen(spectrum.Values, spectrum.Vectors)
+	Canonicalize(spectrum.Vectors)
+	spectrum.Dominant = Dominant(spectrum.Values)
+	return spectrum, nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"gonum.org/v1/gonum/mat"
+)
+
+// ConnectedComponents finds the connected components of the graph with a breadth first search,
+// nodes are connected if there is a nonzero edge in either direction. It returns the number
+// of components and the component of each node.
+func ConnectedComponents(a *mat.Dense) (int, []int) {
+	size, _ := a.Dims()
+	component := make([]int, size)
+	for i := range component {
+		component[i] = -1
+	}
+	count := 0
+	for start := 0; start < size; start++ {
+		if component[start] != -1 {
+			continue
+		}
+		component[start] = count
+		queue := []int{start}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			for next := 0; next < size; next++ {
+				if component[next] != -1 || (a.At(node, next) == 0 && a.At(next, node) == 0) {
+					continue
+				}
+				component[next] = count
+				queue = append(queue, next)
+			}
+		}
+		count++
+	}
+	return count, component
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestConnectedComponents(t *testing.T) {
+	cases := []struct {
+		name      string
+		a         *mat.Dense
+		count     int
+		component []int
+	}{
+		{"path", path, 1, []int{0, 0, 0}},
+		{"two edges", undirected(5, [2]int{0, 3}, [2]int{1, 4}), 3, []int{0, 1, 2, 0, 1}},
+		// a directed edge connects its nodes in either direction
+		{"directed", dense(
+			[]float64{0, 0, 0},
+			[]float64{0, 0, 0},
+			[]float64{1, 0, 0},
+		), 2, []int{0, 1, 0}},
+		{"no edges", mat.NewDense(3, 3, nil), 3, []int{0, 1, 2}},
+	}
+	for _, c := range cases {
+		count, component := ConnectedComponents(c.a)
+		if count != c.count || !reflect.DeepEqual(component, c.component) {
+			t.Errorf("%s: %d components %v, expected %d components %v", c.name, count, component, c.count, c.component)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// MidRanks returns the ascending rank of each score starting at 1, scores tied like in
+// RankScores, in runs of neighbours within Tolerance of each other, share the mean of the ranks
+// they span
+func MidRanks(scores []float64) []float64 {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] < scores[order[j]]
+	})
+	ranks := make([]float64, len(scores))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && math.Abs(scores[order[end]]-scores[order[end-1]]) <= Tolerance {
+			end++
+		}
+		rank := float64(start+end+1) / 2
+		for _, node := range order[start:end] {
+			ranks[node] = rank
+		}
+		start = end
+	}
+	return ranks
+}
+
+// Spearman returns Spearman's rank correlation between two score vectors, it is NaN when
+// every score in either vector is tied
+func Spearman(x, y []float64) float64 {
+	return stat.Correlation(MidRanks(x), MidRanks(y), nil)
+}
+
+// SpearmanRankings returns Spearman's rank correlation between two rankings of the same nodes,
+// such as the ones returned by RankNodes and RankScores
+func SpearmanRankings(a, b []int) float64 {
+	x, y := make([]float64, len(a)), make([]float64, len(b))
+	for i, node := range a {
+		x[node] = float64(i)
+	}
+	for i, node := range b {
+		y[node] = float64(i)
+	}
+	return stat.Correlation(x, y, nil)
+}
+
+// CosineSimilarity returns the cosine similarity of every pair of rows of the embedding, such as
+// the projection onto the principal components. A row with a norm below Tolerance has no
+// direction, so its similarity to every row including itself is 0.
+func CosineSimilarity(embedding *mat.Dense) *mat.Dense {
+	rows, _ := embedding.Dims()
+	squares := make([]float64, rows)
+	for i := range squares {
+		x := embedding.RowView(i)
+		squares[i] = mat.Dot(x, x)
+	}
+	similarity := mat.NewDense(rows, rows, nil)
+	for i := 0; i < rows; i++ {
+		if squares[i] < Tolerance*Tolerance {
+			continue
+		}
+		for j := i; j < rows; j++ {
+			if squares[j] < Tolerance*Tolerance {
+				continue
+			}
+			// the squared norms are multiplied before the square root so that identical rows
+			// are exactly 1, and rounding can still push parallel rows past ±1
+			cosine := mat.Dot(embedding.RowView(i), embedding.RowView(j)) / math.Sqrt(squares[i]*squares[j])
+			cosine = math.Max(-1, math.Min(1, cosine))
+			similarity.Set(i, j, cosine)
+			similarity.Set(j, i, cosine)
+		}
+	}
+	return similarity
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestMidRanks(t *testing.T) {
+	cases := []struct {
+		scores []float64
+		ranks  []float64
+	}{
+		{[]float64{3, 1, 2}, []float64{3, 1, 2}},
+		{[]float64{1, 2, 2, 3}, []float64{1, 2.5, 2.5, 4}},
+		{[]float64{5, 5, 5}, []float64{2, 2, 2}},
+		{[]float64{2, 1 + Tolerance/2, 1, 0}, []float64{4, 2.5, 2.5, 1}},
+		{[]float64{}, []float64{}},
+	}
+	for _, c := range cases {
+		if ranks := MidRanks(c.scores); !reflect.DeepEqual(ranks, c.ranks) {
+			t.Errorf("%v: mid-ranks %v, expected %v", c.scores, ranks, c.ranks)
+		}
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	cases := []struct {
+		name        string
+		x, y        []float64
+		correlation float64
+	}{
+		{"identical order", []float64{1, 2, 3, 4}, []float64{10, 20, 30, 40}, 1},
+		{"reversed", []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}, -1},
+		// the rank correlation only depends on the order, not on the values
+		{"monotone", []float64{-2, -1, 1, 2}, []float64{-8, -1, 1, 8}, 1},
+		// the mid-ranks (1, 2.5, 2.5, 4) against (1, 2, 3, 4) have the covariance 4.5 and the
+		// variances 4.5 and 5
+		{"ties", []float64{1, 2, 2, 3}, []float64{1, 2, 3, 4}, math.Sqrt(.9)},
+		{"constant", []float64{1, 1, 1}, []float64{1, 2, 3}, math.NaN()},
+	}
+	for _, c := range cases {
+		correlation := Spearman(c.x, c.y)
+		if math.IsNaN(c.correlation) != math.IsNaN(correlation) || math.Abs(correlation-c.correlation) > 1e-12 {
+			t.Errorf("%s: correlation %g, expected %g", c.name, correlation, c.correlation)
+		}
+	}
+}
+
+func TestSpearmanRankings(t *testing.T) {
+	cases := []struct {
+		a, b        []int
+		correlation float64
+	}{
+		{[]int{2, 0, 1}, []int{2, 0, 1}, 1},
+		{[]int{0, 1, 2}, []int{2, 1, 0}, -1},
+		// node 1 and node 2 swap places, the positions (0, 1, 2) and (0, 2, 1) correlate by 1/2
+		{[]int{0, 1, 2}, []int{0, 2, 1}, .5},
+	}
+	for _, c := range cases {
+		if correlation := SpearmanRankings(c.a, c.b); math.Abs(correlation-c.correlation) > 1e-12 {
+			t.Errorf("%v and %v: correlation %g, expected %g", c.a, c.b, correlation, c.correlation)
+		}
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	// the rows are (1, 0), (0, 2), (1, 1), (0, 0) and (-3, 0), the zero row has no direction
+	embedding := mat.NewDense(5, 2, []float64{
+		1, 0,
+		0, 2,
+		1, 1,
+		0, 0,
+		-3, 0,
+	})
+	half := math.Sqrt2 / 2
+	expected := mat.NewDense(5, 5, []float64{
+		1, 0, half, 0, -1,
+		0, 1, half, 0, 0,
+		half, half, 1, 0, -half,
+		0, 0, 0, 0, 0,
+		-1, 0, -half, 0, 1,
+	})
+	similarity := CosineSimilarity(embedding)
+	if !mat.EqualApprox(similarity, expected, 1e-15) {
+		t.Errorf("similarity\n%v\nexpected\n%v", mat.Formatted(similarity), mat.Formatted(expected))
+	}
+	// identical rows are exactly 1
+	for i := 0; i < 5; i++ {
+		if i != 3 && similarity.At(i, i) != 1 {
+			t.Errorf("row %d has the similarity %v to itself", i, similarity.At(i, i))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Fiedler returns the algebraic connectivity and the Fiedler vector of the graph, the eigenvector
+// of the second smallest eigenvalue of the Laplacian. Directed graphs are symmetrized.
+func Fiedler(a *mat.Dense) (float64, []float64, error) {
+	size, _ := a.Dims()
+	if size < 2 {
+		return 0, nil, fmt.Errorf("the fiedler vector needs at least 2 nodes, got %d", size)
+	}
+	spectrum, err := Decompose(Laplacian(symmetrize(a)), DecomposeOptions{})
+	if err != nil {
+		return 0, nil, err
+	}
+	order := make([]int, size)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return real(spectrum.Values[order[i]]) < real(spectrum.Values[order[j]])
+	})
+	index := order[1]
+	vector := make([]float64, size)
+	for i := range vector {
+		vector[i] = real(spectrum.Vectors.At(i, index))
+	}
+	return real(spectrum.Values[index]), vector, nil
+}
+
+// Bisect partitions the nodes by the sign of their component of the Fiedler vector, nodes with a
+// negative component are in partition 0 and the others in partition 1. It returns the partition
+// of each node and the edge cut, the weight of the symmetrized edges between the partitions.
+func Bisect(a *mat.Dense, fiedler []float64) ([]int, float64) {
+	partition := make([]int, len(fiedler))
+	for i, v := range fiedler {
+		// components that are zero up to rounding go with the positive side
+		if v > -Tolerance {
+			partition[i] = 1
+		}
+	}
+	cut := 0.0
+	for i := range partition {
+		for j := i + 1; j < len(partition); j++ {
+			if partition[i] != partition[j] {
+				cut += (a.At(i, j) + a.At(j, i)) / 2
+			}
+		}
+	}
+	return partition, cut
+}
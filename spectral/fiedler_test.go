@@ -0,0 +1,91 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFiedler(t *testing.T) {
+	// the laplacian of the path of n nodes has the eigenvalues 2 - 2 cos(πk/n) with the
+	// eigenvectors cos(πk(i+1/2)/n)
+	c1, c3 := math.Cos(math.Pi/8), math.Cos(3*math.Pi/8)
+	norm := math.Sqrt(2 * (c1*c1 + c3*c3))
+	cases := []struct {
+		name         string
+		a            *mat.Dense
+		connectivity float64
+		vector       []float64
+		partition    []int
+		cut          float64
+	}{
+		{"path", undirected(4, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}),
+			2 - math.Sqrt2, []float64{c1 / norm, c3 / norm, -c3 / norm, -c1 / norm}, []int{1, 1, 0, 0}, 1},
+		// a directed path is symmetrized, which halves the weights
+		{"directed path", directed(undirected(4, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3})),
+			1 - math.Sqrt2/2, []float64{c1 / norm, c3 / norm, -c3 / norm, -c1 / norm}, []int{1, 1, 0, 0}, .5},
+		// only the bisection of the barbell is checked
+		{"barbell", barbell, 0, nil, []int{1, 1, 1, 0, 0, 0}, 1},
+	}
+	for _, c := range cases {
+		connectivity, vector, err := Fiedler(c.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the sign of the fiedler vector is arbitrary, so it is fixed by the first node
+		if vector[0] < 0 {
+			for i := range vector {
+				vector[i] = -vector[i]
+			}
+		}
+		if c.vector != nil {
+			if math.Abs(connectivity-c.connectivity) > 1e-9 {
+				t.Errorf("%s: algebraic connectivity %g, expected %g", c.name, connectivity, c.connectivity)
+			}
+			for i, v := range vector {
+				if math.Abs(v-c.vector[i]) > 1e-9 {
+					t.Errorf("%s: fiedler vector %v, expected %v", c.name, vector, c.vector)
+					break
+				}
+			}
+		}
+		partition, cut := Bisect(c.a, vector)
+		if !reflect.DeepEqual(partition, c.partition) || math.Abs(cut-c.cut) > 1e-12 {
+			t.Errorf("%s: partition %v with the cut %g, expected %v with the cut %g", c.name, partition, cut, c.partition, c.cut)
+		}
+	}
+	if _, _, err := Fiedler(mat.NewDense(1, 1, nil)); err == nil {
+		t.Errorf("fiedler vector of a single node: no error")
+	}
+}
+
+func TestBisect(t *testing.T) {
+	a := dense(
+		[]float64{0, 2, 0, 1},
+		[]float64{0, 0, 3, 0},
+		[]float64{0, 3, 0, 0},
+		[]float64{1, 0, 0, 0},
+	)
+	cases := []struct {
+		fiedler   []float64
+		partition []int
+		cut       float64
+	}{
+		// components that are zero up to rounding go with the positive side
+		{[]float64{-1, -Tolerance / 2, 1, 2}, []int{0, 1, 1, 1}, 1 + 1},
+		{[]float64{-1, -2, 1, 1}, []int{0, 0, 1, 1}, 3 + 1},
+		{[]float64{1, 1, 1, 1}, []int{1, 1, 1, 1}, 0},
+	}
+	for _, c := range cases {
+		partition, cut := Bisect(a, c.fiedler)
+		if !reflect.DeepEqual(partition, c.partition) || math.Abs(cut-c.cut) > 1e-12 {
+			t.Errorf("%v: partition %v with the cut %g, expected %v with the cut %g", c.fiedler, partition, cut, c.partition, c.cut)
+		}
+	}
+}
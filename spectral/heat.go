@@ -0,0 +1,46 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// HeatKernel computes the heat kernel exp(-t L) of the Laplacian L of the symmetrized graph by
+// scaling the eigenvalues of L = V Λ V⁻¹, exp(-t L) = V exp(-t Λ) V⁻¹. Row i is the heat on
+// every node at time t after a unit of heat placed on node i diffuses along the edges, so t is
+// the scale of the embedding: for a small t the heat stays near node i, as t grows it spreads
+// over the community of node i and then evens out over its connected component. An eigenvector
+// of λ decays with exp(-t λ), so around t = 1/λ₂, the algebraic connectivity, only the smoothest
+// eigenvectors that separate the communities remain.
+func HeatKernel(a *mat.Dense, t float64) (*mat.Dense, error) {
+	if t < 0 || math.IsNaN(t) || math.IsInf(t, 0) {
+		return nil, fmt.Errorf("heat kernel time %g must be finite and non-negative", t)
+	}
+	spectrum, err := Decompose(Laplacian(symmetrize(a)), DecomposeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	size := len(spectrum.Values)
+	// the laplacian of the symmetrized graph is symmetric, so the eigenpairs are real
+	vectors, scaled := mat.NewDense(size, size, nil), mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		for j, value := range spectrum.Values {
+			v := real(spectrum.Vectors.At(i, j))
+			vectors.Set(i, j, v)
+			scaled.Set(i, j, v*math.Exp(-t*real(value)))
+		}
+	}
+	var inverse mat.Dense
+	if err := inverse.Inverse(vectors); err != nil {
+		return nil, fmt.Errorf("%w: the eigenvectors of the laplacian are singular: %v", ErrEigen, err)
+	}
+	kernel := mat.NewDense(size, size, nil)
+	kernel.Mul(scaled, &inverse)
+	return kernel, nil
+}
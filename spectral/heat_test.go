@@ -0,0 +1,90 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestHeatKernel(t *testing.T) {
+	edge := undirected(2, [2]int{0, 1})
+	// the laplacian of an edge has the eigenvalues 0 and 2, the heat on the other node rises to
+	// 1/2 as (1 - e^-2t)/2
+	pair := func(rate, t float64) *mat.Dense {
+		decay := math.Exp(-rate * t)
+		return dense(
+			[]float64{(1 + decay) / 2, (1 - decay) / 2},
+			[]float64{(1 - decay) / 2, (1 + decay) / 2},
+		)
+	}
+	// the laplacian of the triangle has the eigenvalues 0, 3 and 3, so exp(-tL) is
+	// J/3 + e^-3t (I - J/3)
+	spread := func(t float64) *mat.Dense {
+		kernel := mat.NewDense(3, 3, nil)
+		decay := math.Exp(-3 * t)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				value := (1 - decay) / 3
+				if i == j {
+					value += decay
+				}
+				kernel.Set(i, j, value)
+			}
+		}
+		return kernel
+	}
+	cases := []struct {
+		name   string
+		a      *mat.Dense
+		t      float64
+		kernel *mat.Dense
+	}{
+		{"edge at 0", edge, 0, dense([]float64{1, 0}, []float64{0, 1})},
+		{"edge", edge, .5, pair(2, .5)},
+		// a directed edge is symmetrized to half the weight, which halves the rate
+		{"directed edge", dense([]float64{0, 1}, []float64{0, 0}), .5, pair(1, .5)},
+		{"triangle", triangle, .2, spread(.2)},
+		// the heat evens out over each connected component
+		{"star", star, 50, dense(
+			[]float64{.25, .25, .25, .25},
+			[]float64{.25, .25, .25, .25},
+			[]float64{.25, .25, .25, .25},
+			[]float64{.25, .25, .25, .25},
+		)},
+		{"two edges", undirected(4, [2]int{0, 1}, [2]int{2, 3}), 50, dense(
+			[]float64{.5, .5, 0, 0},
+			[]float64{.5, .5, 0, 0},
+			[]float64{0, 0, .5, .5},
+			[]float64{0, 0, .5, .5},
+		)},
+	}
+	for _, c := range cases {
+		kernel, err := HeatKernel(c.a, c.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the laplacian rows add up to zero, so the kernel keeps the heat of every row
+		size, _ := kernel.Dims()
+		for i := 0; i < size; i++ {
+			if sum := mat.Sum(kernel.RowView(i)); math.Abs(sum-1) > testTolerance {
+				t.Errorf("%s: row %d of the heat kernel adds up to %g", c.name, i, sum)
+			}
+		}
+		if !mat.EqualApprox(kernel, kernel.T(), testTolerance) {
+			t.Errorf("%s: the heat kernel isn't symmetric", c.name)
+		}
+		if !mat.EqualApprox(kernel, c.kernel, testTolerance) {
+			t.Errorf("%s: heat kernel\n%v\nexpected\n%v", c.name, mat.Formatted(kernel), mat.Formatted(c.kernel))
+		}
+	}
+	for _, time := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if _, err := HeatKernel(triangle, time); err == nil {
+			t.Errorf("heat kernel at time %g: no error", time)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// HITS computes the hub and authority scores of each node with power iteration, a(i, j) is
+// the weight of the edge from node i to node j. The hubs converge to the dominant eigenvector
+// of A Aᵀ and the authorities to the dominant eigenvector of Aᵀ A, both have unit length.
+func HITS(a *mat.Dense, tolerance float64, iterations int) (hubs, authorities []float64) {
+	size, _ := a.Dims()
+	hub, authority := mat.NewVecDense(size, nil), mat.NewVecDense(size, nil)
+	for i := 0; i < size; i++ {
+		hub.SetVec(i, 1/math.Sqrt(float64(size)))
+	}
+	normalize := func(v *mat.VecDense) {
+		if norm := mat.Norm(v, 2); norm > 0 {
+			v.ScaleVec(1/norm, v)
+		}
+	}
+	next := mat.NewVecDense(size, nil)
+	for i := 0; i < iterations; i++ {
+		authority.MulVec(a.T(), hub)
+		normalize(authority)
+		next.MulVec(a, authority)
+		normalize(next)
+		delta := 0.0
+		for j := 0; j < size; j++ {
+			delta += math.Abs(next.AtVec(j) - hub.AtVec(j))
+		}
+		hub, next = next, hub
+		if delta < tolerance {
+			break
+		}
+	}
+	return hub.RawVector().Data, authority.RawVector().Data
+}
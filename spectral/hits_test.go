@@ -0,0 +1,55 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestHITS(t *testing.T) {
+	third := 1 / math.Sqrt(3)
+	// Aᵀ A is [[1 1] [1 2]] on the authorities 2 and 3 and A Aᵀ is [[2 1] [1 1]] on the hubs 0
+	// and 1, their dominant eigenvectors are (1, φ) and (φ, 1) for the golden ratio φ
+	phi := (1 + math.Sqrt(5)) / 2
+	norm := math.Sqrt(1 + phi*phi)
+	cases := []struct {
+		name        string
+		a           *mat.Dense
+		hubs        []float64
+		authorities []float64
+	}{
+		{"out star", dense(
+			[]float64{0, 1, 1, 1},
+			[]float64{0, 0, 0, 0},
+			[]float64{0, 0, 0, 0},
+			[]float64{0, 0, 0, 0},
+		), []float64{1, 0, 0, 0}, []float64{0, third, third, third}},
+		{"in star", dense(
+			[]float64{0, 0, 0, 0},
+			[]float64{1, 0, 0, 0},
+			[]float64{1, 0, 0, 0},
+			[]float64{1, 0, 0, 0},
+		), []float64{0, third, third, third}, []float64{1, 0, 0, 0}},
+		{"bipartite", dense(
+			[]float64{0, 0, 1, 1},
+			[]float64{0, 0, 0, 1},
+			[]float64{0, 0, 0, 0},
+			[]float64{0, 0, 0, 0},
+		), []float64{phi / norm, 1 / norm, 0, 0}, []float64{0, 0, 1 / norm, phi / norm}},
+		{"triangle", triangle, []float64{third, third, third}, []float64{third, third, third}},
+	}
+	for _, c := range cases {
+		hubs, authorities := HITS(c.a, 1e-15, 1000)
+		for i := range hubs {
+			if math.Abs(hubs[i]-c.hubs[i]) > 1e-9 || math.Abs(authorities[i]-c.authorities[i]) > 1e-9 {
+				t.Errorf("%s: hubs %v and authorities %v, expected %v and %v", c.name, hubs, authorities, c.hubs, c.authorities)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// KMeans clusters the rows of points into k clusters and returns the cluster of each row,
+// the centroids are initialized deterministically with the farthest point heuristic
+func KMeans(points *mat.Dense, k, iterations int) []int {
+	rows, cols := points.Dims()
+	if k > rows {
+		k = rows
+	}
+	distance := func(i int, centroid []float64) float64 {
+		sum := 0.0
+		for j, c := range centroid {
+			d := points.At(i, j) - c
+			sum += d * d
+		}
+		return sum
+	}
+
+	centroids := make([][]float64, 0, k)
+	centroids = append(centroids, mat.Row(nil, 0, points))
+	for len(centroids) < k {
+		farthest, max := 0, -1.0
+		for i := 0; i < rows; i++ {
+			min := math.MaxFloat64
+			for _, centroid := range centroids {
+				if d := distance(i, centroid); d < min {
+					min = d
+				}
+			}
+			if min > max {
+				farthest, max = i, min
+			}
+		}
+		centroids = append(centroids, mat.Row(nil, farthest, points))
+	}
+
+	clusters := make([]int, rows)
+	for iteration := 0; iteration < iterations; iteration++ {
+		changed := false
+		for i := 0; i < rows; i++ {
+			cluster, min := 0, math.MaxFloat64
+			for c, centroid := range centroids {
+				if d := distance(i, centroid); d < min {
+					cluster, min = c, d
+				}
+			}
+			if iteration == 0 || clusters[i] != cluster {
+				clusters[i], changed = cluster, true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums, counts := make([][]float64, k), make([]int, k)
+		for c := range sums {
+			sums[c] = make([]float64, cols)
+		}
+		for i, cluster := range clusters {
+			counts[cluster]++
+			for j := 0; j < cols; j++ {
+				sums[cluster][j] += points.At(i, j)
+			}
+		}
+		for c, count := range counts {
+			if count == 0 {
+				continue
+			}
+			for j := range sums[c] {
+				centroids[c][j] = sums[c][j] / float64(count)
+			}
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"gonum.org/v1/gonum/mat"
+)
+
+// symmetrize returns (A + Aᵀ)/2 so that directed graphs are treated as undirected
+func symmetrize(a *mat.Dense) *mat.Dense {
+	size, _ := a.Dims()
+	symmetric := mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			symmetric.Set(i, j, (a.At(i, j)+a.At(j, i))/2)
+		}
+	}
+	return symmetric
+}
+
+// ModularityMatrix computes the modularity matrix B = A - k kᵀ/2m of the symmetrized adjacency
+// matrix, where k are the weighted degrees and 2m is their sum. It also returns 2m.
+func ModularityMatrix(a *mat.Dense) (*mat.Dense, float64) {
+	symmetric := symmetrize(a)
+	size, _ := symmetric.Dims()
+	degrees := Degrees(symmetric)
+	total := 0.0
+	for _, degree := range degrees {
+		total += degree
+	}
+	b := mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			value := symmetric.At(i, j)
+			if total > 0 {
+				value -= degrees[i] * degrees[j] / total
+			}
+			b.Set(i, j, value)
+		}
+	}
+	return b, total
+}
+
+// Modularity computes the modularity Q = 1/2m Σ B(i, j) δ(c(i), c(j)) of the community
+// assignment, a graph without edges has zero modularity
+func Modularity(a *mat.Dense, communities []int) float64 {
+	b, total := ModularityMatrix(a)
+	if total == 0 {
+		return 0
+	}
+	size, _ := b.Dims()
+	q := 0.0
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if communities[i] == communities[j] {
+				q += b.At(i, j)
+			}
+		}
+	}
+	return q / total
+}
+
+// split divides the group by the signs of the leading eigenvector of the generalized modularity
+// matrix of the group, it returns false when no split increases the modularity
+func split(b *mat.Dense, total float64, group []int) ([]int, []int, bool) {
+	n := len(group)
+	if n < 2 {
+		return nil, nil, false
+	}
+	bg := mat.NewSymDense(n, nil)
+	for x, i := range group {
+		row := 0.0
+		for _, k := range group {
+			row += b.At(i, k)
+		}
+		for y := x; y < n; y++ {
+			value := b.At(i, group[y])
+			if x == y {
+				value -= row
+			}
+			bg.SetSym(x, y, value)
+		}
+	}
+	var eig mat.EigenSym
+	if !eig.Factorize(bg, true) {
+		return nil, nil, false
+	}
+	// the eigenvalues are in ascending order
+	if values := eig.Values(nil); values[n-1] <= Tolerance {
+		return nil, nil, false
+	}
+	var vectors mat.Dense
+	eig.VectorsTo(&vectors)
+	s := mat.NewVecDense(n, nil)
+	var first, second []int
+	for x, i := range group {
+		if vectors.At(x, n-1) > 0 {
+			s.SetVec(x, 1)
+			first = append(first, i)
+		} else {
+			s.SetVec(x, -1)
+			second = append(second, i)
+		}
+	}
+	if len(first) == 0 || len(second) == 0 {
+		return nil, nil, false
+	}
+	if gain := mat.Inner(s, bg, s) / (2 * total); gain <= Tolerance {
+		return nil, nil, false
+	}
+	return first, second, true
+}
+
+// Communities detects communities with Newman's spectral method, the nodes are split in two by
+// the signs of the leading eigenvector of the modularity matrix and each community is split
+// again until no split increases the modularity. Directed graphs are symmetrized. It returns
+// the number of communities and the community of each node, numbered in order of their first node.
+func Communities(a *mat.Dense) (int, []int) {
+	size, _ := a.Dims()
+	community := make([]int, size)
+	b, total := ModularityMatrix(a)
+	if total == 0 || size == 0 {
+		return 1, community
+	}
+
+	group := make([]int, size)
+	for i := range group {
+		group[i] = i
+	}
+	groups, found := [][]int{group}, 0
+	for len(groups) > 0 {
+		group := groups[len(groups)-1]
+		groups = groups[:len(groups)-1]
+		first, second, ok := split(b, total, group)
+		if ok {
+			groups = append(groups, second, first)
+			continue
+		}
+		for _, node := range group {
+			community[node] = found
+		}
+		found++
+	}
+
+	labels, count := make(map[int]int), 0
+	for node, c := range community {
+		if _, ok := labels[c]; !ok {
+			labels[c] = count
+			count++
+		}
+		community[node] = labels[c]
+	}
+	return count, community
+}
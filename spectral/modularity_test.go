@@ -0,0 +1,73 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// barbell is two triangles joined by the edge between nodes 2 and 3
+var barbell = undirected(6, [2]int{0, 1}, [2]int{1, 2}, [2]int{0, 2}, [2]int{3, 4}, [2]int{4, 5}, [2]int{3, 5}, [2]int{2, 3})
+
+// directed returns the upper triangle of a, which is a after it is symmetrized
+func directed(a *mat.Dense) *mat.Dense {
+	size, _ := a.Dims()
+	upper := mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		for j := i + 1; j < size; j++ {
+			upper.Set(i, j, a.At(i, j))
+		}
+	}
+	return upper
+}
+
+func TestModularity(t *testing.T) {
+	// Q = Σ_c L_c/m - (d_c/2m)² for the edges L_c within and the degree d_c of each community,
+	// the barbell has m = 7 and the degrees 2, 2, 3, 3, 2 and 2
+	cases := []struct {
+		name        string
+		a           *mat.Dense
+		communities []int
+		modularity  float64
+	}{
+		{"triangles", barbell, []int{0, 0, 0, 1, 1, 1}, 2 * (3./7 - .25)},
+		{"directed triangles", directed(barbell), []int{0, 0, 0, 1, 1, 1}, 2 * (3./7 - .25)},
+		{"one community", barbell, []int{0, 0, 0, 0, 0, 0}, 0},
+		{"singletons", barbell, []int{0, 1, 2, 3, 4, 5}, -34. / 196},
+		{"no edges", mat.NewDense(3, 3, nil), []int{0, 1, 2}, 0},
+	}
+	for _, c := range cases {
+		if q := Modularity(c.a, c.communities); math.Abs(q-c.modularity) > 1e-12 {
+			t.Errorf("%s: modularity %g, expected %g", c.name, q, c.modularity)
+		}
+	}
+}
+
+func TestCommunities(t *testing.T) {
+	cases := []struct {
+		name      string
+		a         *mat.Dense
+		count     int
+		community []int
+	}{
+		{"barbell", barbell, 2, []int{0, 0, 0, 1, 1, 1}},
+		{"directed barbell", directed(barbell), 2, []int{0, 0, 0, 1, 1, 1}},
+		{"two triangles", undirected(6, [2]int{0, 2}, [2]int{2, 4}, [2]int{0, 4}, [2]int{1, 3}, [2]int{3, 5}, [2]int{1, 5}),
+			2, []int{0, 1, 0, 1, 0, 1}},
+		// no split of the triangle increases the modularity
+		{"triangle", triangle, 1, []int{0, 0, 0}},
+		{"no edges", mat.NewDense(3, 3, nil), 1, []int{0, 0, 0}},
+	}
+	for _, c := range cases {
+		count, community := Communities(c.a)
+		if count != c.count || !reflect.DeepEqual(community, c.community) {
+			t.Errorf("%s: %d communities %v, expected %d communities %v", c.name, count, community, c.count, c.community)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Degrees returns the weighted degree of each node, the row sums of the adjacency matrix
+func Degrees(a *mat.Dense) []float64 {
+	rows, cols := a.Dims()
+	degrees := make([]float64, rows)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			degrees[i] += a.At(i, j)
+		}
+	}
+	return degrees
+}
+
+// Normalize normalizes the adjacency matrix, the modes are "none", "row" for D^-1 A,
+// "symmetric" for D^-1/2 A D^-1/2 and "laplacian" for D - A. Nodes with zero degree
+// are left as zero rows and columns.
+func Normalize(a *mat.Dense, mode string) (*mat.Dense, error) {
+	size, _ := a.Dims()
+	degrees := Degrees(a)
+	normalized := mat.NewDense(size, size, nil)
+	switch mode {
+	case "", "none":
+		normalized.Copy(a)
+	case "row":
+		for i := 0; i < size; i++ {
+			if degrees[i] == 0 {
+				continue
+			}
+			for j := 0; j < size; j++ {
+				normalized.Set(i, j, a.At(i, j)/degrees[i])
+			}
+		}
+	case "symmetric":
+		for i := 0; i < size; i++ {
+			for j := 0; j < size; j++ {
+				if degrees[i] == 0 || degrees[j] == 0 {
+					continue
+				}
+				normalized.Set(i, j, a.At(i, j)/math.Sqrt(degrees[i]*degrees[j]))
+			}
+		}
+	case "laplacian":
+		normalized = Laplacian(a)
+	default:
+		return nil, fmt.Errorf("unknown normalization %q", mode)
+	}
+	return normalized, nil
+}
+
+// Laplacian computes the graph Laplacian L = D - A
+func Laplacian(a *mat.Dense) *mat.Dense {
+	size, _ := a.Dims()
+	degrees := Degrees(a)
+	laplacian := mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			laplacian.Set(i, j, -a.At(i, j))
+		}
+		laplacian.Set(i, i, degrees[i]-a.At(i, i))
+	}
+	return laplacian
+}
+
+// ZeroEigenvalues counts the eigenvalues with magnitude below tolerance, for the Laplacian
+// this is the number of connected components
+func ZeroEigenvalues(values []complex128, tolerance float64) int {
+	count := 0
+	for _, value := range values {
+		if cmplx.Abs(value) < tolerance {
+			count++
+		}
+	}
+	return count
+}
+
+// SpectralGap returns the difference between the two largest eigenvalue magnitudes, a large gap
+// means the dominant eigenvector is well separated and that power iteration converges quickly
+func SpectralGap(values []complex128) float64 {
+	first, second := 0.0, 0.0
+	for _, value := range values {
+		if abs := cmplx.Abs(value); abs > first {
+			first, second = abs, first
+		} else if abs > second {
+			second = abs
+		}
+	}
+	if len(values) < 2 {
+		return 0
+	}
+	return first - second
+}
+
+// AlgebraicConnectivity returns the Fiedler value, the second smallest eigenvalue of the
+// Laplacian, which is zero if and only if the graph is disconnected
+func AlgebraicConnectivity(values []complex128) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	reals := make([]float64, len(values))
+	for i, value := range values {
+		reals[i] = real(value)
+	}
+	sort.Float64s(reals)
+	return reals[1]
+}
+
+// StripSelfLoops zeroes the diagonal of the adjacency matrix in place and returns the number of
+// self-loops removed. Self-loops add their weight to the diagonal, which for a regular graph shifts
+// every eigenvalue by the same amount and otherwise inflates the scores of the looped nodes.
+func StripSelfLoops(a *mat.Dense) int {
+	size, _ := a.Dims()
+	stripped := 0
+	for i := 0; i < size; i++ {
+		if a.At(i, i) != 0 {
+			a.Set(i, i, 0)
+			stripped++
+		}
+	}
+	return stripped
+}
+
+// Threshold zeroes the entries of the adjacency matrix in place whose magnitude is below the
+// threshold and returns the number of edges pruned. When symmetric is true an edge and its
+// mirror are pruned together by the larger of their magnitudes and counted once, so that a
+// symmetric matrix stays symmetric.
+func Threshold(a *mat.Dense, threshold float64, symmetric bool) int {
+	size, _ := a.Dims()
+	pruned := 0
+	for i := 0; i < size; i++ {
+		start := 0
+		if symmetric {
+			start = i
+		}
+		for j := start; j < size; j++ {
+			magnitude := math.Abs(a.At(i, j))
+			if symmetric {
+				magnitude = math.Max(magnitude, math.Abs(a.At(j, i)))
+			}
+			if magnitude == 0 || magnitude >= threshold {
+				continue
+			}
+			a.Set(i, j, 0)
+			if symmetric {
+				a.Set(j, i, 0)
+			}
+			pruned++
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNormalize(t *testing.T) {
+	// a weighted path with the degrees 2, 3 and 1 and an isolated node
+	a := dense(
+		[]float64{0, 2, 0, 0},
+		[]float64{2, 0, 1, 0},
+		[]float64{0, 1, 0, 0},
+		[]float64{0, 0, 0, 0},
+	)
+	if degrees := Degrees(a); !mat.Equal(mat.NewVecDense(4, degrees), mat.NewVecDense(4, []float64{2, 3, 1, 0})) {
+		t.Errorf("degrees %v, expected [2 3 1 0]", degrees)
+	}
+	cases := []struct {
+		mode       string
+		normalized *mat.Dense
+	}{
+		{"", a},
+		{"none", a},
+		{"row", dense(
+			[]float64{0, 1, 0, 0},
+			[]float64{2. / 3, 0, 1. / 3, 0},
+			[]float64{0, 1, 0, 0},
+			[]float64{0, 0, 0, 0},
+		)},
+		{"symmetric", dense(
+			[]float64{0, 2 / math.Sqrt(6), 0, 0},
+			[]float64{2 / math.Sqrt(6), 0, 1 / math.Sqrt(3), 0},
+			[]float64{0, 1 / math.Sqrt(3), 0, 0},
+			[]float64{0, 0, 0, 0},
+		)},
+		{"laplacian", dense(
+			[]float64{2, -2, 0, 0},
+			[]float64{-2, 3, -1, 0},
+			[]float64{0, -1, 1, 0},
+			[]float64{0, 0, 0, 0},
+		)},
+	}
+	original := mat.DenseCopyOf(a)
+	for _, c := range cases {
+		normalized, err := Normalize(a, c.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mat.EqualApprox(normalized, c.normalized, testTolerance) {
+			t.Errorf("%q: normalized\n%v\nexpected\n%v", c.mode, mat.Formatted(normalized), mat.Formatted(c.normalized))
+		}
+		if !mat.Equal(a, original) {
+			t.Fatalf("%q: the adjacency matrix was modified", c.mode)
+		}
+	}
+	if _, err := Normalize(a, "column"); err == nil {
+		t.Errorf("unknown normalization: no error")
+	}
+}
+
+func TestLaplacian(t *testing.T) {
+	edges := undirected(4, [2]int{0, 1}, [2]int{2, 3})
+	looped := mat.DenseCopyOf(path)
+	looped.Set(1, 1, 5)
+	cases := []struct {
+		name       string
+		a          *mat.Dense
+		values     []complex128
+		components int
+	}{
+		{"path", path, []complex128{3, 1, 0}, 1},
+		{"triangle", triangle, []complex128{3, 3, 0}, 1},
+		{"star", star, []complex128{4, 1, 1, 0}, 1},
+		{"two edges", edges, []complex128{2, 2, 0, 0}, 2},
+		// a self-loop adds to the degree and to the diagonal, so it doesn't change the laplacian
+		{"self-loop", looped, []complex128{3, 1, 0}, 1},
+		{"no edges", mat.NewDense(3, 3, nil), []complex128{0, 0, 0}, 3},
+	}
+	for _, c := range cases {
+		laplacian := Laplacian(c.a)
+		size, _ := c.a.Dims()
+		for i := 0; i < size; i++ {
+			sum := 0.0
+			for j := 0; j < size; j++ {
+				sum += laplacian.At(i, j)
+			}
+			if math.Abs(sum) > testTolerance {
+				t.Errorf("%s: row %d of the laplacian adds up to %g", c.name, i, sum)
+			}
+		}
+		spectrum, err := Decompose(laplacian, DecomposeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, expected := sortedValues(spectrum.Values), sortedValues(c.values)
+		for i := range expected {
+			if math.Abs(real(actual[i])-real(expected[i])) > 1e-9 || math.Abs(imag(actual[i])) > 1e-9 {
+				t.Errorf("%s: laplacian eigenvalues %v, expected %v", c.name, spectrum.Values, c.values)
+				break
+			}
+		}
+		if zeros := ZeroEigenvalues(spectrum.Values, 1e-9); zeros != c.components {
+			t.Errorf("%s: %d zero eigenvalues, expected %d", c.name, zeros, c.components)
+		}
+		if components, _ := ConnectedComponents(c.a); components != c.components {
+			t.Errorf("%s: %d connected components, expected %d", c.name, components, c.components)
+		}
+	}
+}
+
+func TestSpectralGap(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []complex128
+		gap    float64
+	}{
+		{"triangle", []complex128{2, -1, -1}, 1},
+		// the eigenvalues of a bipartite graph come in pairs ±λ, so there is no gap
+		{"path", []complex128{math.Sqrt2, 0, -math.Sqrt2}, 0},
+		{"complex", []complex128{1, 3i}, 2},
+		{"unsorted", []complex128{.5, -1, 4, 2}, 2},
+		{"single", []complex128{5}, 0},
+		{"empty", nil, 0},
+	}
+	for _, c := range cases {
+		if gap := SpectralGap(c.values); math.Abs(gap-c.gap) > testTolerance {
+			t.Errorf("%s: spectral gap %g, expected %g", c.name, gap, c.gap)
+		}
+	}
+}
+
+func TestAlgebraicConnectivity(t *testing.T) {
+	cases := []struct {
+		name         string
+		a            *mat.Dense
+		connectivity float64
+	}{
+		{"path", path, 1},
+		{"triangle", triangle, 3},
+		{"star", star, 1},
+		// the second smallest laplacian eigenvalue is zero exactly when the graph is disconnected
+		{"two edges", undirected(4, [2]int{0, 1}, [2]int{2, 3}), 0},
+		{"single node", mat.NewDense(1, 1, nil), 0},
+	}
+	for _, c := range cases {
+		spectrum, err := Decompose(Laplacian(c.a), DecomposeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if connectivity := AlgebraicConnectivity(spectrum.Values); math.Abs(connectivity-c.connectivity) > 1e-9 {
+			t.Errorf("%s: algebraic connectivity %g, expected %g", c.name, connectivity, c.connectivity)
+		}
+	}
+}
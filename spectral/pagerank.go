@@ -0,0 +1,78 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PageRank computes the page rank of each node with power iteration, a(i, j) is the
+// weight of the edge from node i to node j and nodes without out edges link to every node
+func PageRank(a *mat.Dense, damping, tolerance float64, iterations int) []float64 {
+	size, _ := a.Dims()
+	out := make([]float64, size)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			out[i] += a.At(i, j)
+		}
+	}
+
+	// the column stochastic transition matrix
+	transition := mat.NewDense(size, size, nil)
+	for j := 0; j < size; j++ {
+		for i := 0; i < size; i++ {
+			if out[j] == 0 {
+				transition.Set(i, j, 1/float64(size))
+				continue
+			}
+			transition.Set(i, j, a.At(j, i)/out[j])
+		}
+	}
+
+	rank, next := mat.NewVecDense(size, nil), mat.NewVecDense(size, nil)
+	for i := 0; i < size; i++ {
+		rank.SetVec(i, 1/float64(size))
+	}
+	teleport := (1 - damping) / float64(size)
+	for i := 0; i < iterations; i++ {
+		next.MulVec(transition, rank)
+		delta := 0.0
+		for j := 0; j < size; j++ {
+			value := damping*next.AtVec(j) + teleport
+			delta += math.Abs(value - rank.AtVec(j))
+			next.SetVec(j, value)
+		}
+		rank, next = next, rank
+		if delta < tolerance {
+			break
+		}
+	}
+	return rank.RawVector().Data
+}
+
+// RankScores ranks the nodes by descending score. Neighbouring scores in that order within
+// Tolerance of each other are tied, so a run of scores that are each within Tolerance of the next
+// is one tie, and ties are ordered by ascending node index so that rankings are reproducible.
+func RankScores(scores []float64) []int {
+	ranking := make([]int, len(scores))
+	for i := range ranking {
+		ranking[i] = i
+	}
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return scores[ranking[i]] > scores[ranking[j]]
+	})
+	for start := 0; start < len(ranking); {
+		end := start + 1
+		for end < len(ranking) && math.Abs(scores[ranking[end-1]]-scores[ranking[end]]) <= Tolerance {
+			end++
+		}
+		sort.Ints(ranking[start:end])
+		start = end
+	}
+	return ranking
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPageRank(t *testing.T) {
+	const d = .85
+	// the center of the star gets c = d 3 l + (1-d)/4 from its leaves and each leaf gets
+	// l = d c/3 + (1-d)/4, so c = (3d+1)/(4(1+d))
+	center := (3*d + 1) / (4 * (1 + d))
+	// node 1 has no out edges and links to both nodes, so r0 = d r1/2 + (1-d)/2 = 1/(2+d)
+	dangling := 1 / (2 + d)
+	// node 0 has the out weights 3 and 1 and gets every edge of nodes 1 and 2, so
+	// r0 = d (r1+r2) + (1-d)/3 with r1+r2 = d r0 + 2 (1-d)/3
+	hub := (1 - d) / 3 * (1 + 2*d) / (1 - d*d)
+	cases := []struct {
+		name    string
+		a       *mat.Dense
+		damping float64
+		rank    []float64
+	}{
+		{"cycle", cycle, d, []float64{1. / 3, 1. / 3, 1. / 3}},
+		{"star", star, d, []float64{center, (1 - center) / 3, (1 - center) / 3, (1 - center) / 3}},
+		{"dangling", dense([]float64{0, 1}, []float64{0, 0}), d, []float64{dangling, 1 - dangling}},
+		{"no edges", mat.NewDense(4, 4, nil), d, []float64{.25, .25, .25, .25}},
+		{"no damping", star, 0, []float64{.25, .25, .25, .25}},
+		{"weighted", dense(
+			[]float64{0, 3, 1},
+			[]float64{1, 0, 0},
+			[]float64{1, 0, 0},
+		), d, []float64{hub, .75*d*hub + (1-d)/3, .25*d*hub + (1-d)/3}},
+	}
+	for _, c := range cases {
+		rank := PageRank(c.a, c.damping, 1e-15, 10000)
+		sum := 0.0
+		for _, value := range rank {
+			sum += value
+		}
+		for i, value := range rank {
+			if math.Abs(value-c.rank[i]) > 1e-9 {
+				t.Errorf("%s: page rank %v, expected %v", c.name, rank, c.rank)
+				break
+			}
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("%s: the page rank adds up to %g", c.name, sum)
+		}
+	}
+}
+
+func TestRankScores(t *testing.T) {
+	cases := []struct {
+		name    string
+		scores  []float64
+		ranking []int
+	}{
+		{"distinct", []float64{1, 3, 2}, []int{1, 2, 0}},
+		{"equal", []float64{1, 1, 1}, []int{0, 1, 2}},
+		{"pairs", []float64{2, 1, 2, 1}, []int{0, 2, 1, 3}},
+		{"within tolerance", []float64{.5, 1, 1 + Tolerance/2}, []int{1, 2, 0}},
+		// each score is within Tolerance of the next, so the run is one tie
+		{"run", []float64{1, 1 + .8*Tolerance, 1 + 1.6*Tolerance, 0}, []int{0, 1, 2, 3}},
+		{"beyond tolerance", []float64{1, 1 + 2*Tolerance}, []int{1, 0}},
+		{"empty", []float64{}, []int{}},
+	}
+	for _, c := range cases {
+		ranking := RankScores(c.scores)
+		if !reflect.DeepEqual(ranking, c.ranking) {
+			t.Errorf("%s: ranking %v, expected %v", c.name, ranking, c.ranking)
+		}
+	}
+}
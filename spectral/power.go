@@ -0,0 +1,97 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Convergence are the diagnostics of a power iteration
+type Convergence struct {
+	// Iterations is the number of iterations that ran
+	Iterations int
+	// Converged is false when iters was reached or ctx was done before the eigenvector changed by
+	// less than tol
+	Converged bool
+	// Residual is ||A x - λ x|| for the returned eigenvalue λ and eigenvector x
+	Residual float64
+}
+
+// PowerIteration computes the dominant eigenvalue and the unit length dominant eigenvector
+// of m, iterating until the eigenvector changes by less than tol or iters is reached. When ctx
+// is done it returns the estimate of the last iteration and an error wrapping ErrCanceled.
+// Convergence is slow when the two largest eigenvalue magnitudes are close, the residual shows
+// how far the result is from an eigenpair.
+func PowerIteration(ctx context.Context, m *mat.Dense, iters int, tol float64) (float64, []float64, Convergence, error) {
+	size, _ := m.Dims()
+	start := make([]float64, size)
+	for i := range start {
+		start[i] = 1 / math.Sqrt(float64(size))
+	}
+	return PowerIterationFrom(ctx, m, start, iters, tol)
+}
+
+// PowerIterationFrom is PowerIteration starting from the vector start instead of the uniform
+// vector, starting from the eigenvector of a similar matrix takes fewer iterations
+func PowerIterationFrom(ctx context.Context, m *mat.Dense, start []float64, iters int, tol float64) (float64, []float64, Convergence, error) {
+	size, _ := m.Dims()
+	x, next := mat.NewVecDense(size, nil), mat.NewVecDense(size, nil)
+	norm := 0.0
+	for _, v := range start {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	for i := 0; i < size; i++ {
+		if norm == 0 {
+			x.SetVec(i, 1/math.Sqrt(float64(size)))
+			continue
+		}
+		x.SetVec(i, start[i]/norm)
+	}
+	var err error
+	var convergence Convergence
+	for i := 0; i < iters; i++ {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w: power iteration stopped after %d iterations: %v", ErrCanceled, i, ctx.Err())
+			break
+		}
+		convergence.Iterations++
+		next.MulVec(m, x)
+		norm := mat.Norm(next, 2)
+		if norm == 0 {
+			// x is an eigenvector of the eigenvalue 0
+			convergence.Converged = true
+			return 0, x.RawVector().Data, convergence, nil
+		}
+		next.ScaleVec(1/norm, next)
+		// fix the sign so that the iterates can be compared
+		sum := 0.0
+		for j := 0; j < size; j++ {
+			sum += next.AtVec(j)
+		}
+		if sum < 0 {
+			next.ScaleVec(-1, next)
+		}
+		delta := 0.0
+		for j := 0; j < size; j++ {
+			d := next.AtVec(j) - x.AtVec(j)
+			delta += d * d
+		}
+		x, next = next, x
+		if math.Sqrt(delta) < tol {
+			convergence.Converged = true
+			break
+		}
+	}
+	next.MulVec(m, x)
+	value := mat.Dot(x, next)
+	next.AddScaledVec(next, -value, x)
+	convergence.Residual = mat.Norm(next, 2)
+	return value, x.RawVector().Data, convergence, err
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPowerIteration(t *testing.T) {
+	third := 1 / math.Sqrt(3)
+	cases := []struct {
+		name   string
+		a      *mat.Dense
+		value  float64
+		vector []float64
+	}{
+		{"triangle", triangle, 2, []float64{third, third, third}},
+		{"complete", undirected(4, [2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3}, [2]int{1, 2}, [2]int{1, 3}, [2]int{2, 3}),
+			3, []float64{.5, .5, .5, .5}},
+		{"weighted", dense([]float64{2, 1}, []float64{1, 2}), 3, []float64{math.Sqrt2 / 2, math.Sqrt2 / 2}},
+		{"diagonal", dense([]float64{3, 0}, []float64{0, 1}), 3, []float64{1, 0}},
+		// the dominant eigenvector of an upper triangular matrix is (1, 1) for the eigenvalue 2
+		{"directed", dense([]float64{1, 1}, []float64{0, 2}), 2, []float64{math.Sqrt2 / 2, math.Sqrt2 / 2}},
+		{"no edges", mat.NewDense(2, 2, nil), 0, []float64{math.Sqrt2 / 2, math.Sqrt2 / 2}},
+	}
+	for _, c := range cases {
+		value, vector, _, err := PowerIteration(context.Background(), c.a, 1000, 1e-12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(value-c.value) > 1e-9 {
+			t.Errorf("%s: eigenvalue %g, expected %g", c.name, value, c.value)
+		}
+		for i, v := range vector {
+			if math.Abs(v-c.vector[i]) > 1e-9 {
+				t.Errorf("%s: eigenvector %v, expected %v", c.name, vector, c.vector)
+				break
+			}
+		}
+	}
+
+	// the demo matrix of the command is connected with a self-loop, so its dominant eigenvalue is
+	// simple and the iteration converges to the eigenpair of largest magnitude that mat.Eigen finds
+	demo := dense(
+		[]float64{0, 1, 0, 1, 1},
+		[]float64{1, 0, 1, 0, 1},
+		[]float64{0, 1, 0, 1, 1},
+		[]float64{1, 0, 1, 0, 1},
+		[]float64{1, 1, 1, 1, 1},
+	)
+	var eigen mat.Eigen
+	if !eigen.Factorize(demo, mat.EigenRight) {
+		t.Fatal("eigendecomposition of the demo matrix failed")
+	}
+	values := eigen.Values(nil)
+	var vectors mat.CDense
+	eigen.VectorsTo(&vectors)
+	dominant := 0
+	for i, v := range values {
+		if cmplx.Abs(v) > cmplx.Abs(values[dominant]) {
+			dominant = i
+		}
+	}
+	value, vector, _, err := PowerIteration(context.Background(), demo, 1000, 1e-12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(value-real(values[dominant])) > 1e-9 || math.Abs(imag(values[dominant])) > 1e-9 {
+		t.Errorf("demo: eigenvalue %g, expected %v", value, values[dominant])
+	}
+	// both eigenvectors have unit length and are only defined up to sign, so they agree when the
+	// magnitude of their inner product is 1
+	reference := make([]float64, len(vector))
+	for i := range reference {
+		reference[i] = real(vectors.At(i, dominant))
+	}
+	x, y := mat.NewVecDense(len(vector), vector), mat.NewVecDense(len(reference), reference)
+	if cosine := mat.Dot(x, y) / (mat.Norm(x, 2) * mat.Norm(y, 2)); math.Abs(math.Abs(cosine)-1) > 1e-12 {
+		t.Errorf("demo: eigenvector %v, expected %v up to sign", vector, reference)
+	}
+}
+
+func TestPowerIterationCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	csr := NewCSR(3, []Edge{{0, 1, 1}, {1, 2, 1}, {0, 2, 1}}, false)
+	cases := []struct {
+		name  string
+		power func() (float64, []float64, Convergence, error)
+		start []float64
+	}{
+		{"dense", func() (float64, []float64, Convergence, error) {
+			return PowerIteration(ctx, triangle, 1000, 1e-12)
+		}, []float64{1 / math.Sqrt(3), 1 / math.Sqrt(3), 1 / math.Sqrt(3)}},
+		{"warm", func() (float64, []float64, Convergence, error) {
+			return PowerIterationFrom(ctx, triangle, []float64{3, 0, 4}, 1000, 1e-12)
+		}, []float64{.6, 0, .8}},
+		{"sparse", func() (float64, []float64, Convergence, error) {
+			return PowerIterationCSR(ctx, csr, 1000, 1e-12)
+		}, []float64{1 / math.Sqrt(3), 1 / math.Sqrt(3), 1 / math.Sqrt(3)}},
+	}
+	for _, c := range cases {
+		// a done context stops before the first iteration with the normalized start vector
+		_, vector, convergence, err := c.power()
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("%s: error %v, expected %v", c.name, err, ErrCanceled)
+		}
+		if convergence.Iterations != 0 || convergence.Converged {
+			t.Errorf("%s: %d iterations converged %t after the context was done", c.name, convergence.Iterations, convergence.Converged)
+		}
+		for i, v := range vector {
+			if math.Abs(v-c.start[i]) > 1e-12 {
+				t.Errorf("%s: vector %v, expected the start vector %v", c.name, vector, c.start)
+				break
+			}
+		}
+	}
+}
+
+func TestPowerIterationConvergence(t *testing.T) {
+	cases := []struct {
+		name        string
+		a           *mat.Dense
+		iters       int
+		convergence Convergence
+	}{
+		// the uniform start is the dominant eigenvector of the triangle
+		{"triangle", triangle, 100, Convergence{Iterations: 1, Converged: true}},
+		{"no edges", mat.NewDense(3, 3, nil), 100, Convergence{Iterations: 1, Converged: true}},
+		// after 3 iterations x is (8, 1)/√65 with the eigenvalue estimate 129/65, which leaves the
+		// residual (8, -64)/(65 √65)
+		{"diagonal", dense([]float64{2, 0}, []float64{0, 1}), 3, Convergence{Iterations: 3, Residual: math.Hypot(8, 64) / 65 / math.Sqrt(65)}},
+		// the eigenvalues ±√2 of the path have the same magnitude, so the iterates alternate
+		// between (1, 1, 1)/√3 and (1, 2, 1)/√6 and after an even number of iterations
+		// A x - 4/3 x = (-1, 2, -1)/(3 √3)
+		{"path", path, 10, Convergence{Iterations: 10, Residual: math.Sqrt2 / 3}},
+	}
+	for _, c := range cases {
+		_, _, convergence, err := PowerIteration(context.Background(), c.a, c.iters, 1e-12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if convergence.Iterations != c.convergence.Iterations || convergence.Converged != c.convergence.Converged ||
+			math.Abs(convergence.Residual-c.convergence.Residual) > 1e-12 {
+			t.Errorf("%s: convergence %+v, expected %+v", c.name, convergence, c.convergence)
+		}
+	}
+
+	// a converged estimate of a non-trivial start is an eigenpair up to the tolerance
+	_, _, convergence, err := PowerIteration(context.Background(), dense([]float64{2, 1}, []float64{1, 3}), 1000, 1e-12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !convergence.Converged || convergence.Iterations < 2 || convergence.Iterations == 1000 || convergence.Residual > 1e-10 {
+		t.Errorf("convergence %+v, expected a converged eigenpair", convergence)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// SignedLaplacian computes the signed Laplacian L = D̄ - A of a graph with negative edges, where
+// D̄ is the diagonal matrix of the absolute degrees Σ|a_ij|. Unlike the Laplacian it is positive
+// semidefinite for symmetric signed graphs.
+func SignedLaplacian(a *mat.Dense) *mat.Dense {
+	size, _ := a.Dims()
+	laplacian := mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		degree := 0.0
+		for j := 0; j < size; j++ {
+			degree += math.Abs(a.At(i, j))
+			laplacian.Set(i, j, -a.At(i, j))
+		}
+		laplacian.Set(i, i, degree-a.At(i, i))
+	}
+	return laplacian
+}
+
+// Balance is the structural balance of a signed graph
+type Balance struct {
+	// Smallest is the smallest eigenvalue of the signed Laplacian, it is 0 when a connected
+	// component is balanced and larger the more edges have to flip sign to balance the graph
+	Smallest float64
+	// Balanced is the number of balanced connected components, the multiplicity of the 0 eigenvalue
+	Balanced int
+	// Factions splits the nodes by the sign of their component of the eigenvector of Smallest, for
+	// a balanced connected graph the positive edges are within the factions and the negative
+	// edges between them
+	Factions []int
+}
+
+// StructuralBalance eigendecomposes the signed Laplacian of the symmetrized graph. A signed graph
+// is structurally balanced when its nodes split into two factions with only positive edges within
+// and only negative edges between them, which holds for a connected component exactly when the
+// signed Laplacian restricted to it has the eigenvalue 0.
+func StructuralBalance(a *mat.Dense) (*Balance, error) {
+	spectrum, err := Decompose(SignedLaplacian(symmetrize(a)), DecomposeOptions{})
+	if err != nil {
+		return nil, err
+	}
+	size := len(spectrum.Values)
+	order := make([]int, size)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return real(spectrum.Values[order[i]]) < real(spectrum.Values[order[j]])
+	})
+	balance := &Balance{
+		Smallest: real(spectrum.Values[order[0]]),
+		Factions: make([]int, size),
+	}
+	for _, value := range spectrum.Values {
+		if math.Abs(real(value)) < Tolerance {
+			balance.Balanced++
+		}
+	}
+	// the eigenvalues of a balanced component are zero up to rounding
+	if math.Abs(balance.Smallest) < Tolerance {
+		balance.Smallest = 0
+	}
+	for i := range balance.Factions {
+		// components that are zero up to rounding go with the positive side like in Bisect
+		if real(spectrum.Vectors.At(i, order[0])) > -Tolerance {
+			balance.Factions[i] = 1
+		}
+	}
+	return balance, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// signedTriangle returns the triangle with the signs of the edges 0-1, 1-2 and 0-2
+func signedTriangle(s01, s12, s02 float64) *mat.Dense {
+	return dense(
+		[]float64{0, s01, s02},
+		[]float64{s01, 0, s12},
+		[]float64{s02, s12, 0},
+	)
+}
+
+func TestSignedLaplacian(t *testing.T) {
+	a := dense(
+		[]float64{0, 2, -1},
+		[]float64{2, 0, -3},
+		[]float64{-1, -3, 0},
+	)
+	// the diagonal is the absolute degree and the off diagonal entries are -A
+	expected := dense(
+		[]float64{3, -2, 1},
+		[]float64{-2, 5, 3},
+		[]float64{1, 3, 4},
+	)
+	if laplacian := SignedLaplacian(a); !mat.Equal(laplacian, expected) {
+		t.Errorf("signed laplacian\n%v\nexpected\n%v", mat.Formatted(laplacian), mat.Formatted(expected))
+	}
+	// without negative edges it is the laplacian
+	if laplacian := SignedLaplacian(triangle); !mat.Equal(laplacian, Laplacian(triangle)) {
+		t.Errorf("signed laplacian of the triangle\n%v\nexpected the laplacian", mat.Formatted(laplacian))
+	}
+}
+
+func TestStructuralBalance(t *testing.T) {
+	cases := []struct {
+		name     string
+		a        *mat.Dense
+		smallest float64
+		balanced int
+		// factions is nil when the graph isn't balanced, the labels of the factions are arbitrary
+		factions []int
+	}{
+		// nodes 0 and 1 are friends and both are enemies of node 2
+		{"balanced", signedTriangle(1, -1, -1), 0, 1, []int{0, 0, 1}},
+		{"positive", signedTriangle(1, 1, 1), 0, 1, []int{0, 0, 0}},
+		// three mutual enemies can't split into two factions, the signed laplacian 2I - A has the
+		// eigenvalues 4, 1 and 1
+		{"negative", signedTriangle(-1, -1, -1), 1, 0, nil},
+		// an odd number of negative edges on a cycle is unbalanced
+		{"one negative", signedTriangle(1, 1, -1), 1, 0, nil},
+		{"directed", directed(signedTriangle(1, -1, -1)), 0, 1, []int{0, 0, 1}},
+	}
+	for _, c := range cases {
+		balance, err := StructuralBalance(c.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(balance.Smallest-c.smallest) > 1e-9 || balance.Balanced != c.balanced {
+			t.Errorf("%s: smallest eigenvalue %g with %d balanced components, expected %g with %d", c.name, balance.Smallest, balance.Balanced, c.smallest, c.balanced)
+		}
+		if c.factions == nil {
+			continue
+		}
+		same := true
+		for i := range c.factions {
+			for j := range c.factions {
+				same = same && (c.factions[i] == c.factions[j]) == (balance.Factions[i] == balance.Factions[j])
+			}
+		}
+		if !same {
+			t.Errorf("%s: factions %v, expected %v", c.name, balance.Factions, c.factions)
+		}
+	}
+}
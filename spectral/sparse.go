@@ -0,0 +1,204 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Edge is a weighted edge of a graph
+type Edge struct {
+	Source, Destination int
+	Weight              float64
+}
+
+// CSR is a sparse adjacency matrix in compressed sparse row format, the entries of row i are
+// Columns[Offsets[i]:Offsets[i+1]] and Values[Offsets[i]:Offsets[i+1]]
+type CSR struct {
+	Size    int
+	Offsets []int
+	Columns []int
+	Values  []float64
+}
+
+// NewCSR builds a sparse adjacency matrix from the edges, undirected edges are mirrored and
+// a repeated edge replaces the earlier one like it does in a dense matrix
+func NewCSR(size int, edges []Edge, directed bool) *CSR {
+	entries := make([]Edge, 0, 2*len(edges))
+	for _, edge := range edges {
+		entries = append(entries, edge)
+		if !directed {
+			entries = append(entries, Edge{Source: edge.Destination, Destination: edge.Source, Weight: edge.Weight})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+		return entries[i].Destination < entries[j].Destination
+	})
+
+	m := &CSR{
+		Size:    size,
+		Offsets: make([]int, size+1),
+		Columns: make([]int, 0, len(entries)),
+		Values:  make([]float64, 0, len(entries)),
+	}
+	for i, entry := range entries {
+		if i+1 < len(entries) && entries[i+1].Source == entry.Source && entries[i+1].Destination == entry.Destination {
+			continue
+		}
+		if entry.Weight == 0 {
+			continue
+		}
+		m.Columns = append(m.Columns, entry.Destination)
+		m.Values = append(m.Values, entry.Weight)
+		m.Offsets[entry.Source+1]++
+	}
+	for i := 0; i < size; i++ {
+		m.Offsets[i+1] += m.Offsets[i]
+	}
+	return m
+}
+
+// NonZero returns the number of nonzero entries
+func (m *CSR) NonZero() int {
+	return len(m.Values)
+}
+
+// Density returns the fraction of the entries that are nonzero
+func (m *CSR) Density() float64 {
+	return float64(m.NonZero()) / (float64(m.Size) * float64(m.Size))
+}
+
+// Bytes returns the approximate memory used by the matrix
+func (m *CSR) Bytes() int {
+	return 8 * (len(m.Offsets) + len(m.Columns) + len(m.Values))
+}
+
+// Dense converts the matrix to a dense matrix
+func (m *CSR) Dense() *mat.Dense {
+	dense := mat.NewDense(m.Size, m.Size, nil)
+	for i := 0; i < m.Size; i++ {
+		for k := m.Offsets[i]; k < m.Offsets[i+1]; k++ {
+			dense.Set(i, m.Columns[k], m.Values[k])
+		}
+	}
+	return dense
+}
+
+// MulVec computes dst = m x
+func (m *CSR) MulVec(dst, x []float64) {
+	for i := 0; i < m.Size; i++ {
+		sum := 0.0
+		for k := m.Offsets[i]; k < m.Offsets[i+1]; k++ {
+			sum += m.Values[k] * x[m.Columns[k]]
+		}
+		dst[i] = sum
+	}
+}
+
+// ConnectedComponentsCSR is ConnectedComponents for a sparse matrix
+func ConnectedComponentsCSR(m *CSR) (int, []int) {
+	neighbors := make([][]int, m.Size)
+	for i := 0; i < m.Size; i++ {
+		for k := m.Offsets[i]; k < m.Offsets[i+1]; k++ {
+			j := m.Columns[k]
+			neighbors[i] = append(neighbors[i], j)
+			neighbors[j] = append(neighbors[j], i)
+		}
+	}
+	component := make([]int, m.Size)
+	for i := range component {
+		component[i] = -1
+	}
+	count := 0
+	for start := 0; start < m.Size; start++ {
+		if component[start] != -1 {
+			continue
+		}
+		component[start] = count
+		queue := []int{start}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			for _, next := range neighbors[node] {
+				if component[next] == -1 {
+					component[next] = count
+					queue = append(queue, next)
+				}
+			}
+		}
+		count++
+	}
+	return count, component
+}
+
+// PowerIterationCSR is PowerIteration for a sparse matrix
+func PowerIterationCSR(ctx context.Context, m *CSR, iters int, tol float64) (float64, []float64, Convergence, error) {
+	size := m.Size
+	x, next := make([]float64, size), make([]float64, size)
+	for i := range x {
+		x[i] = 1 / math.Sqrt(float64(size))
+	}
+	var err error
+	var convergence Convergence
+	for i := 0; i < iters; i++ {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w: power iteration stopped after %d iterations: %v", ErrCanceled, i, ctx.Err())
+			break
+		}
+		convergence.Iterations++
+		m.MulVec(next, x)
+		norm := 0.0
+		for _, v := range next {
+			norm += v * v
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			// x is an eigenvector of the eigenvalue 0
+			convergence.Converged = true
+			return 0, x, convergence, nil
+		}
+		// fix the sign so that the iterates can be compared
+		sum := 0.0
+		for j := range next {
+			next[j] /= norm
+			sum += next[j]
+		}
+		if sum < 0 {
+			for j := range next {
+				next[j] = -next[j]
+			}
+		}
+		delta := 0.0
+		for j := range next {
+			d := next[j] - x[j]
+			delta += d * d
+		}
+		x, next = next, x
+		if math.Sqrt(delta) < tol {
+			convergence.Converged = true
+			break
+		}
+	}
+	m.MulVec(next, x)
+	value := 0.0
+	for j := range x {
+		value += x[j] * next[j]
+	}
+	residual := 0.0
+	for j := range x {
+		d := next[j] - value*x[j]
+		residual += d * d
+	}
+	convergence.Residual = math.Sqrt(residual)
+	return value, x, convergence, err
+}
@@ -0,0 +1,364 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package spectral implements spectral analysis of graphs
+package spectral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+const (
+	// Tolerance is the tolerance used when comparing matrix entries
+	Tolerance = 1e-9
+)
+
+var (
+	// ErrEigen is returned when the eigendecomposition fails
+	ErrEigen = errors.New("eigendecomposition failed")
+	// ErrPCA is returned when the principal component analysis fails
+	ErrPCA = errors.New("principal component analysis failed")
+	// ErrCanceled is returned with the partial result when the context of a computation is done
+	ErrCanceled = errors.New("computation canceled")
+)
+
+// PCAModes are the ways complex eigenvectors are reduced to real features for the principal
+// component analysis: "real" keeps the real parts, which loses the imaginary parts that directed
+// graphs can have, "abs" uses the magnitudes, which loses the phases and so collapses the nodes of a
+// directed cycle, whose eigenvector entries all have the same magnitude, to one point, and
+// "realimag" uses the real and imaginary parts as separate features
+var PCAModes = []string{"real", "abs", "realimag"}
+
+// PCAMatrices are the matrices the principal components are computed from: "covariance" uses the
+// features as they are and "correlation" standardizes each feature to zero mean and unit variance
+// first, so that features with a large scale don't dominate the components
+var PCAMatrices = []string{"covariance", "correlation"}
+
+// EigenSides are the eigenvectors that are computed: "right" solves A v = λ v, "left" solves
+// uᴴ A = λ uᴴ and is used in place of the right eigenvectors, and "both" computes the two in
+// one factorization
+var EigenSides = []string{"right", "left", "both"}
+
+// Graph is a graph represented by its adjacency matrix, PCAMode is one of PCAModes and
+// defaults to "real", PCAMatrix is one of PCAMatrices and defaults to "covariance" and Side is
+// one of EigenSides and defaults to "right". PCAWeights are the weights of the nodes in the
+// principal component analysis, nil weighs every node equally. Embedding has a row of features
+// for each node that replaces the eigenvector features in the principal component analysis,
+// such as the HeatKernel.
+type Graph struct {
+	Adjacency  *mat.Dense
+	PCAMode    string
+	PCAMatrix  string
+	PCAWeights []float64
+	Embedding  *mat.Dense
+	Side       string
+	spectrum   *Spectrum
+	// warm is the dominant eigenvector estimate that power iteration restarts from after
+	// the adjacency matrix is updated
+	warm []float64
+}
+
+// WarmIterations is the most power iterations Rank runs after the adjacency matrix is updated
+const WarmIterations = 1000
+
+// NewGraph creates a new graph from an adjacency matrix
+func NewGraph(adjacency *mat.Dense) *Graph {
+	return &Graph{
+		Adjacency: adjacency,
+	}
+}
+
+// Size returns the number of nodes in the graph
+func (g *Graph) Size() int {
+	size, _ := g.Adjacency.Dims()
+	return size
+}
+
+// Spectrum returns the eigendecomposition of the adjacency matrix with the eigenvectors of Side
+func (g *Graph) Spectrum() (*Spectrum, error) {
+	if g.spectrum != nil {
+		return g.spectrum, nil
+	}
+	spectrum, err := Decompose(g.Adjacency, DecomposeOptions{Side: g.Side})
+	if err != nil {
+		return nil, err
+	}
+	g.spectrum, g.warm = spectrum, nil
+	return spectrum, nil
+}
+
+// Eigen returns the eigenvectors and eigenvalues of the adjacency matrix sorted by
+// descending eigenvalue magnitude, the eigenvectors are the left ones when Side is "left"
+func (g *Graph) Eigen() (*mat.CDense, []complex128, error) {
+	spectrum, err := g.Spectrum()
+	if err != nil {
+		return nil, nil, err
+	}
+	return spectrum.Vectors, spectrum.Values, nil
+}
+
+// LeftEigen returns the left eigenvectors and the eigenvalues sorted like Eigen, the left
+// eigenvectors are only computed when Side is "left" or "both"
+func (g *Graph) LeftEigen() (*mat.CDense, []complex128, error) {
+	spectrum, err := g.Spectrum()
+	if err != nil {
+		return nil, nil, err
+	}
+	if spectrum.Left == nil {
+		return nil, nil, fmt.Errorf("left eigenvectors aren't computed for eigen side %s", g.Side)
+	}
+	return spectrum.Left, spectrum.Values, nil
+}
+
+// AddEdge sets the weight of the edge from node i to node j in the adjacency matrix in place,
+// an undirected graph needs the edge from j to i set too. The eigendecomposition is dropped
+// and the dominant eigenvector is kept to warm start the next Rank.
+func (g *Graph) AddEdge(i, j int, w float64) {
+	if g.warm == nil && g.spectrum != nil {
+		size := g.Size()
+		g.warm = make([]float64, size)
+		for k := range g.warm {
+			g.warm[k] = real(g.spectrum.Vectors.At(k, g.spectrum.Dominant))
+		}
+	}
+	g.spectrum = nil
+	g.Adjacency.Set(i, j, w)
+}
+
+// RemoveEdge removes the edge from node i to node j like AddEdge with a zero weight
+func (g *Graph) RemoveEdge(i, j int) {
+	g.AddEdge(i, j, 0)
+}
+
+// Rank ranks the nodes by the dominant eigenvector. After AddEdge the dominant eigenvector is
+// estimated with power iteration started from the previous one instead of a decomposition,
+// which is much faster for a small change but is only an approximation: it converges slowly
+// when the two largest eigenvalue magnitudes are close and not at all when they are equal,
+// as they are for bipartite graphs.
+func (g *Graph) Rank() ([]int, error) {
+	if g.warm != nil {
+		// the background context is never done, so there is no error
+		_, x, _, _ := PowerIterationFrom(context.Background(), g.Adjacency, g.warm, WarmIterations, Tolerance)
+		g.warm = x
+		scores := make([]float64, len(x))
+		for i, v := range x {
+			scores[i] = math.Abs(v)
+		}
+		return RankScores(scores), nil
+	}
+	spectrum, err := g.Spectrum()
+	if err != nil {
+		return nil, err
+	}
+	return RankNodes(spectrum), nil
+}
+
+// Features reduces the complex eigenvectors to a real feature per column, or two with "realimag"
+func Features(vectors *mat.CDense, mode string) (*mat.Dense, error) {
+	rows, cols := vectors.Dims()
+	switch mode {
+	case "", "real":
+		features := mat.NewDense(rows, cols, nil)
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				features.Set(i, j, real(vectors.At(i, j)))
+			}
+		}
+		return features, nil
+	case "abs":
+		features := mat.NewDense(rows, cols, nil)
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				features.Set(i, j, cmplx.Abs(vectors.At(i, j)))
+			}
+		}
+		return features, nil
+	case "realimag":
+		features := mat.NewDense(rows, 2*cols, nil)
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				features.Set(i, 2*j, real(vectors.At(i, j)))
+				features.Set(i, 2*j+1, imag(vectors.At(i, j)))
+			}
+		}
+		return features, nil
+	}
+	return nil, fmt.Errorf("unknown pca mode %s, expected one of %s", mode, strings.Join(PCAModes, ", "))
+}
+
+// pca computes the principal components of the eigenvector features or the Embedding, which are
+// standardized first for the correlation matrix
+func (g *Graph) pca() (*stat.PC, *mat.Dense, error) {
+	var ranks *mat.Dense
+	if g.Embedding != nil {
+		ranks = mat.DenseCopyOf(g.Embedding)
+	} else {
+		vectors, _, err := g.Eigen()
+		if err != nil {
+			return nil, nil, err
+		}
+		ranks, err = Features(vectors, g.PCAMode)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	size, features := ranks.Dims()
+	if g.PCAWeights != nil && len(g.PCAWeights) != size {
+		return nil, nil, fmt.Errorf("%w: %d pca weights for %d nodes", ErrPCA, len(g.PCAWeights), size)
+	}
+	switch g.PCAMatrix {
+	case "", "covariance":
+	case "correlation":
+		column := make([]float64, size)
+		for j := 0; j < features; j++ {
+			mat.Col(column, j, ranks)
+			mean, std := stat.MeanStdDev(column, g.PCAWeights)
+			for i, v := range column {
+				// a constant feature carries no information and is zero after centering
+				if std < Tolerance {
+					ranks.Set(i, j, 0)
+					continue
+				}
+				ranks.Set(i, j, (v-mean)/std)
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown pca matrix %s, expected one of %s", g.PCAMatrix, strings.Join(PCAMatrices, ", "))
+	}
+
+	var pc stat.PC
+	ok := pc.PrincipalComponents(ranks, g.PCAWeights)
+	if !ok {
+		return nil, nil, ErrPCA
+	}
+	return &pc, ranks, nil
+}
+
+// Project projects the eigenvector features onto the first k principal components
+func (g *Graph) Project(k int) (*mat.Dense, error) {
+	size := g.Size()
+	if k < 1 || k > size {
+		return nil, fmt.Errorf("%d components is out of range for a graph with %d nodes", k, size)
+	}
+	pc, ranks, err := g.pca()
+	if err != nil {
+		return nil, err
+	}
+	var proj mat.Dense
+	var vec mat.Dense
+	pc.VectorsTo(&vec)
+	features, _ := vec.Dims()
+	proj.Mul(ranks, vec.Slice(0, features, 0, k))
+	return &proj, nil
+}
+
+// Variances returns the variance captured by each principal component
+func (g *Graph) Variances() ([]float64, error) {
+	pc, _, err := g.pca()
+	if err != nil {
+		return nil, err
+	}
+	return pc.VarsTo(nil), nil
+}
+
+// IsSymmetric determines if the matrix is symmetric within tolerance
+func IsSymmetric(a *mat.Dense, tolerance float64) bool {
+	rows, cols := a.Dims()
+	if rows != cols {
+		return false
+	}
+	for i := 0; i < rows; i++ {
+		for j := i + 1; j < cols; j++ {
+			if math.Abs(a.At(i, j)-a.At(j, i)) > tolerance {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SortEigen sorts the eigenvalues by descending magnitude and reorders the
+// eigenvector columns to match
+func SortEigen(values []complex128, vectors *mat.CDense) []complex128 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return cmplx.Abs(values[order[i]]) > cmplx.Abs(values[order[j]])
+	})
+
+	rows, _ := vectors.Dims()
+	sorted, reordered := make([]complex128, len(values)), mat.NewCDense(rows, len(values), nil)
+	for j, k := range order {
+		sorted[j] = values[k]
+		for i := 0; i < rows; i++ {
+			reordered.Set(i, j, vectors.At(i, k))
+		}
+	}
+	vectors.Copy(reordered)
+	return sorted
+}
+
+// Canonicalize fixes the arbitrary phase of each eigenvector column in place so that its largest
+// magnitude entry is real and positive, the first of the entries within Tolerance of the largest
+// magnitude is used so that the result is reproducible
+func Canonicalize(vectors *mat.CDense) {
+	rows, cols := vectors.Dims()
+	for j := 0; j < cols; j++ {
+		max := 0.0
+		for i := 0; i < rows; i++ {
+			if abs := cmplx.Abs(vectors.At(i, j)); abs > max {
+				max = abs
+			}
+		}
+		if max == 0 {
+			continue
+		}
+		pivot := 0
+		for i := 0; i < rows; i++ {
+			if cmplx.Abs(vectors.At(i, j)) >= max-Tolerance {
+				pivot = i
+				break
+			}
+		}
+		v := vectors.At(pivot, j)
+		rotation := cmplx.Conj(v) / complex(cmplx.Abs(v), 0)
+		for i := 0; i < rows; i++ {
+			vectors.Set(i, j, vectors.At(i, j)*rotation)
+		}
+	}
+}
+
+// Dominant returns the index of the largest magnitude eigenvalue
+func Dominant(values []complex128) int {
+	index, max := 0, 0.0
+	for i, value := range values {
+		if abs := cmplx.Abs(value); abs > max {
+			index, max = i, abs
+		}
+	}
+	return index
+}
+
+// RankNodes ranks the nodes by the magnitude of the real part of the dominant eigenvector,
+// ties are broken by RankScores
+func RankNodes(spectrum *Spectrum) []int {
+	size, _ := spectrum.Vectors.Dims()
+	scores := make([]float64, size)
+	for i := range scores {
+		scores[i] = math.Abs(real(spectrum.Vectors.At(i, spectrum.Dominant)))
+	}
+	return RankScores(scores)
+}
@@ -0,0 +1,564 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// testTolerance is how far a computed value can be from its expected value
+const testTolerance = 1e-9
+
+// dense returns the square matrix with the rows
+func dense(rows ...[]float64) *mat.Dense {
+	a := mat.NewDense(len(rows), len(rows), nil)
+	for i, row := range rows {
+		a.SetRow(i, row)
+	}
+	return a
+}
+
+// undirected returns the adjacency matrix of the undirected graph with the edges
+func undirected(size int, edges ...[2]int) *mat.Dense {
+	a := mat.NewDense(size, size, nil)
+	for _, edge := range edges {
+		a.Set(edge[0], edge[1], 1)
+		a.Set(edge[1], edge[0], 1)
+	}
+	return a
+}
+
+var (
+	// triangle has the eigenvalues 2, -1 and -1
+	triangle = undirected(3, [2]int{0, 1}, [2]int{1, 2}, [2]int{0, 2})
+	// path has the eigenvalues √2, 0 and -√2
+	path = undirected(3, [2]int{0, 1}, [2]int{1, 2})
+	// star has the eigenvalues √3, 0, 0 and -√3 with the center 0
+	star = undirected(4, [2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3})
+	// cycle is the directed cycle of 3 nodes, its eigenvalues are the cube roots of unity
+	cycle = dense(
+		[]float64{0, 1, 0},
+		[]float64{0, 0, 1},
+		[]float64{1, 0, 0},
+	)
+)
+
+// sortedValues returns the values sorted by descending real and then imaginary part
+func sortedValues(values []complex128) []complex128 {
+	sorted := append([]complex128(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if math.Abs(real(sorted[i])-real(sorted[j])) > testTolerance {
+			return real(sorted[i]) > real(sorted[j])
+		}
+		return imag(sorted[i]) > imag(sorted[j])
+	})
+	return sorted
+}
+
+// checkEigenpairs reports the columns of the vectors that aren't eigenvectors of a
+func checkEigenpairs(t *testing.T, name string, a *mat.Dense, values []complex128, vectors *mat.CDense) {
+	t.Helper()
+	size, _ := a.Dims()
+	for k, value := range values {
+		norm := 0.0
+		for i := 0; i < size; i++ {
+			norm += cmplx.Abs(vectors.At(i, k)) * cmplx.Abs(vectors.At(i, k))
+			product := complex128(0)
+			for j := 0; j < size; j++ {
+				product += complex(a.At(i, j), 0) * vectors.At(j, k)
+			}
+			if cmplx.Abs(product-value*vectors.At(i, k)) > 1e-6 {
+				t.Errorf("%s: column %d isn't an eigenvector of %v", name, k, value)
+				break
+			}
+		}
+		if math.Abs(norm-1) > 1e-6 {
+			t.Errorf("%s: column %d has the norm %g", name, k, math.Sqrt(norm))
+		}
+	}
+}
+
+func TestGraphEigen(t *testing.T) {
+	root := cmplx.Exp(2i * math.Pi / 3)
+	cases := []struct {
+		name   string
+		a      *mat.Dense
+		values []complex128
+	}{
+		{"triangle", triangle, []complex128{2, -1, -1}},
+		{"path", path, []complex128{math.Sqrt2, 0, -math.Sqrt2}},
+		{"star", star, []complex128{complex(math.Sqrt(3), 0), 0, 0, complex(-math.Sqrt(3), 0)}},
+		{"cycle", cycle, []complex128{1, root, cmplx.Conj(root)}},
+	}
+	for _, c := range cases {
+		graph := NewGraph(mat.DenseCopyOf(c.a))
+		if size, _ := c.a.Dims(); graph.Size() != size {
+			t.Errorf("%s: size %d, expected %d", c.name, graph.Size(), size)
+		}
+		vectors, values, err := graph.Eigen()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(values); i++ {
+			if cmplx.Abs(values[i]) > cmplx.Abs(values[i-1])+testTolerance {
+				t.Errorf("%s: the eigenvalues %v aren't sorted by descending magnitude", c.name, values)
+			}
+		}
+		actual, expected := sortedValues(values), sortedValues(c.values)
+		for i := range expected {
+			if cmplx.Abs(actual[i]-expected[i]) > testTolerance {
+				t.Errorf("%s: eigenvalues %v, expected %v", c.name, values, c.values)
+				break
+			}
+		}
+		checkEigenpairs(t, c.name, c.a, values, vectors)
+		if _, _, err := graph.LeftEigen(); err == nil {
+			t.Errorf("%s: left eigenvectors without the left side", c.name)
+		}
+	}
+}
+
+func TestGraphProject(t *testing.T) {
+	graph := NewGraph(mat.DenseCopyOf(star))
+	vectors, _, err := graph.Eigen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the principal components are a rotation of the centered features, so the variances add up
+	// to the total variance of the real parts of the eigenvectors and the projection onto every
+	// component keeps the distances between the nodes
+	total, column := 0.0, make([]float64, 4)
+	for j := 0; j < 4; j++ {
+		for i := range column {
+			column[i] = real(vectors.At(i, j))
+		}
+		total += stat.Variance(column, nil)
+	}
+	variances, err := graph.Variances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := floats.Sum(variances); math.Abs(sum-total) > testTolerance {
+		t.Errorf("the variances add up to %g, expected %g", sum, total)
+	}
+	for i := 1; i < len(variances); i++ {
+		if variances[i] > variances[i-1]+testTolerance {
+			t.Errorf("the variances %v aren't sorted by descending variance", variances)
+		}
+	}
+	for _, k := range []int{1, 2, 4} {
+		projection, err := graph.Project(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows, cols := projection.Dims(); rows != 4 || cols != k {
+			t.Errorf("projection onto %d components is %dx%d", k, rows, cols)
+		}
+		if k != 4 {
+			continue
+		}
+		for a := 0; a < 4; a++ {
+			for b := a + 1; b < 4; b++ {
+				projected, original := 0.0, 0.0
+				for j := 0; j < 4; j++ {
+					projected += math.Pow(projection.At(a, j)-projection.At(b, j), 2)
+					original += math.Pow(real(vectors.At(a, j))-real(vectors.At(b, j)), 2)
+				}
+				if math.Abs(projected-original) > testTolerance {
+					t.Errorf("the distance between nodes %d and %d is %g projected, expected %g", a, b, math.Sqrt(projected), math.Sqrt(original))
+				}
+			}
+		}
+	}
+	for _, k := range []int{0, 5} {
+		if _, err := graph.Project(k); err == nil {
+			t.Errorf("projection onto %d components of 4 nodes", k)
+		}
+	}
+}
+
+func TestRankNodes(t *testing.T) {
+	cases := []struct {
+		name     string
+		a        *mat.Dense
+		spectrum *Spectrum
+		ranking  []int
+	}{
+		// the leaves of the star have the same score and are ranked by index after the center
+		{"star", star, nil, []int{0, 1, 2, 3}},
+		{"triangle", triangle, nil, []int{0, 1, 2}},
+		// the ranking is by the magnitude of the real parts of the dominant column
+		{"dominant", nil, &Spectrum{
+			Values: []complex128{1, 2},
+			Vectors: mat.NewCDense(3, 2, []complex128{
+				1, .6,
+				0, -.8,
+				0, .6i,
+			}),
+			Dominant: 1,
+		}, []int{1, 0, 2}},
+	}
+	for _, c := range cases {
+		spectrum := c.spectrum
+		if spectrum == nil {
+			var err error
+			spectrum, err = Decompose(c.a, DecomposeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if ranking := RankNodes(spectrum); !reflect.DeepEqual(ranking, c.ranking) {
+			t.Errorf("%s: ranking %v, expected %v", c.name, ranking, c.ranking)
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	vectors := mat.NewCDense(2, 2, []complex128{
+		3 + 4i, -1,
+		-2i, .5 - .5i,
+	})
+	cases := []struct {
+		mode     string
+		features *mat.Dense
+	}{
+		{"", mat.NewDense(2, 2, []float64{3, -1, 0, .5})},
+		{"real", mat.NewDense(2, 2, []float64{3, -1, 0, .5})},
+		{"abs", mat.NewDense(2, 2, []float64{5, 1, 2, math.Sqrt2 / 2})},
+		{"realimag", mat.NewDense(2, 4, []float64{
+			3, 4, -1, 0,
+			0, -2, .5, -.5,
+		})},
+	}
+	for _, c := range cases {
+		features, err := Features(vectors, c.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mat.EqualApprox(features, c.features, testTolerance) {
+			t.Errorf("%q: features\n%v\nexpected\n%v", c.mode, mat.Formatted(features), mat.Formatted(c.features))
+		}
+	}
+	if _, err := Features(vectors, "phase"); err == nil {
+		t.Errorf("unknown pca mode: no error")
+	}
+
+	// the nodes of the directed cycle are rotations of each other, which only the imaginary parts
+	// of its eigenvectors show, so with realimag the projected nodes are equally far apart
+	graph := NewGraph(mat.DenseCopyOf(cycle))
+	graph.PCAMode = "realimag"
+	projection, err := graph.Project(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	distance := func(a, b int) float64 {
+		sum := 0.0
+		for j := 0; j < 3; j++ {
+			sum += math.Pow(projection.At(a, j)-projection.At(b, j), 2)
+		}
+		return math.Sqrt(sum)
+	}
+	if d01, d12, d02 := distance(0, 1), distance(1, 2), distance(0, 2); math.Abs(d01-d12) > 1e-9 || math.Abs(d01-d02) > 1e-9 || d01 < 1e-3 {
+		t.Errorf("the projected nodes of the cycle are %g, %g and %g apart", d01, d12, d02)
+	}
+
+	// every eigenvector entry of the cycle has the magnitude 1/√3, so with abs the nodes have the
+	// same features and project to one point
+	graph = NewGraph(mat.DenseCopyOf(cycle))
+	graph.PCAMode = "abs"
+	projection, err = graph.Project(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d01, d12, d02 := distance(0, 1), distance(1, 2), distance(0, 2); d01 > 1e-9 || d12 > 1e-9 || d02 > 1e-9 {
+		t.Errorf("with abs the projected nodes of the cycle are %g, %g and %g apart, expected one point", d01, d12, d02)
+	}
+}
+
+// randomGraph returns a random undirected graph with the edge probability p
+func randomGraph(rng *rand.Rand, size int, p float64) *mat.Dense {
+	a := mat.NewDense(size, size, nil)
+	for i := 0; i < size; i++ {
+		for j := i + 1; j < size; j++ {
+			if rng.Float64() < p {
+				a.Set(i, j, 1)
+				a.Set(j, i, 1)
+			}
+		}
+	}
+	return a
+}
+
+func TestAddEdge(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	graph := NewGraph(randomGraph(rng, 20, .3))
+	if _, err := graph.Rank(); err != nil {
+		t.Fatal(err)
+	}
+	// the random graph has triangles, so it isn't bipartite and power iteration converges
+	for step := 0; step < 10; step++ {
+		i, j := rng.Intn(20), rng.Intn(20)
+		if i == j {
+			continue
+		}
+		w := 1 - graph.Adjacency.At(i, j)
+		if w == 0 {
+			graph.RemoveEdge(i, j)
+			graph.RemoveEdge(j, i)
+		} else {
+			graph.AddEdge(i, j, w)
+			graph.AddEdge(j, i, w)
+		}
+		if graph.Adjacency.At(i, j) != w || graph.Adjacency.At(j, i) != w {
+			t.Fatalf("step %d: the edge between %d and %d wasn't set to %g", step, i, j, w)
+		}
+		warm, err := graph.Rank()
+		if err != nil {
+			t.Fatal(err)
+		}
+		scores := append([]float64(nil), graph.warm...)
+
+		// the warm started ranking is the ranking of a new decomposition
+		spectrum, err := Decompose(graph.Adjacency, DecomposeOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ranking := RankNodes(spectrum); !reflect.DeepEqual(warm, ranking) {
+			t.Errorf("step %d: warm ranking %v, expected %v", step, warm, ranking)
+		}
+		for k, score := range scores {
+			if expected := real(spectrum.Vectors.At(k, spectrum.Dominant)); math.Abs(math.Abs(score)-math.Abs(expected)) > 1e-6 {
+				t.Errorf("step %d: warm score %g of node %d, expected %g", step, score, k, expected)
+				break
+			}
+		}
+		// the decomposition was dropped by AddEdge, so the graph decomposes the new matrix
+		values := spectrum.Values
+		_, actual, err := graph.Eigen()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k := range values {
+			if cmplx.Abs(actual[k]-values[k]) > 1e-9 {
+				t.Errorf("step %d: eigenvalues %v, expected %v", step, actual, values)
+				break
+			}
+		}
+	}
+}
+
+// BenchmarkRank benchmarks ranking the nodes of a random graph after toggling the edge between
+// nodes 0 and 1, with a new decomposition and with power iteration warm started by AddEdge
+func BenchmarkRank(b *testing.B) {
+	for _, warm := range []bool{false, true} {
+		name := "full"
+		if warm {
+			name = "warm"
+		}
+		b.Run(name, func(b *testing.B) {
+			graph := NewGraph(randomGraph(rand.New(rand.NewSource(1)), 200, .1))
+			if _, err := graph.Rank(); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				w := 1 - graph.Adjacency.At(0, 1)
+				if warm {
+					graph.AddEdge(0, 1, w)
+					graph.AddEdge(1, 0, w)
+				} else {
+					graph.Adjacency.Set(0, 1, w)
+					graph.Adjacency.Set(1, 0, w)
+					graph = NewGraph(graph.Adjacency)
+				}
+				if _, err := graph.Rank(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSortEigen(t *testing.T) {
+	cases := []struct {
+		values []complex128
+		sorted []complex128
+		// order is the column of the unsorted vectors each sorted column comes from
+		order []int
+	}{
+		{[]complex128{1, -3, 2i, .5}, []complex128{-3, 2i, 1, .5}, []int{1, 2, 0, 3}},
+		// eigenvalues of the same magnitude keep their order
+		{[]complex128{-1, 1, 1i}, []complex128{-1, 1, 1i}, []int{0, 1, 2}},
+		{[]complex128{0, 1 + 1i, 1 - 1i}, []complex128{1 + 1i, 1 - 1i, 0}, []int{1, 2, 0}},
+	}
+	for _, c := range cases {
+		// column k of the vectors is (k, -k) to tell the columns apart
+		size := len(c.values)
+		vectors := mat.NewCDense(2, size, nil)
+		for k := 0; k < size; k++ {
+			vectors.Set(0, k, complex(float64(k), 0))
+			vectors.Set(1, k, complex(0, -float64(k)))
+		}
+		sorted := SortEigen(c.values, vectors)
+		if !reflect.DeepEqual(sorted, c.sorted) {
+			t.Errorf("%v: sorted %v, expected %v", c.values, sorted, c.sorted)
+		}
+		for j, k := range c.order {
+			if vectors.At(0, j) != complex(float64(k), 0) || vectors.At(1, j) != complex(0, -float64(k)) {
+				t.Errorf("%v: column %d isn't the eigenvector of column %d", c.values, j, k)
+			}
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	cases := []struct {
+		name      string
+		vector    []complex128
+		canonical []complex128
+	}{
+		{"real", []complex128{.6, -.8}, []complex128{-.6, .8}},
+		{"complex", []complex128{1, -2i}, []complex128{1i, 2}},
+		// the first of the entries with the largest magnitude is made real and positive
+		{"tie", []complex128{-1, 1}, []complex128{1, -1}},
+		{"tie within tolerance", []complex128{1i, 1 + Tolerance/2}, []complex128{1, -1i - complex(0, Tolerance/2)}},
+		{"zero", []complex128{0, 0}, []complex128{0, 0}},
+	}
+	for _, c := range cases {
+		// the same eigenvector with any phase has the same canonical form
+		for _, phase := range []complex128{1, -1, 1i, cmplx.Exp(.7i)} {
+			vectors := mat.NewCDense(len(c.vector), 1, nil)
+			for i, v := range c.vector {
+				vectors.Set(i, 0, v*phase)
+			}
+			Canonicalize(vectors)
+			for i, v := range c.canonical {
+				if cmplx.Abs(vectors.At(i, 0)-v) > testTolerance {
+					t.Errorf("%s with phase %v: canonical entry %d is %v, expected %v", c.name, phase, i, vectors.At(i, 0), v)
+				}
+			}
+		}
+	}
+
+	// the decomposition canonicalizes the eigenvectors, so the dominant eigenvector of a non-negative
+	// connected graph is positive
+	spectrum, err := Decompose(star, DecomposeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if v := spectrum.Vectors.At(i, Perron(spectrum.Values)); real(v) <= 0 || math.Abs(imag(v)) > testTolerance {
+			t.Errorf("entry %d of the perron eigenvector is %v", i, v)
+		}
+	}
+}
+
+func TestPCAMatrix(t *testing.T) {
+	// the second feature has a thousand times the scale of the first, the third is constant
+	embedding := mat.NewDense(4, 3, []float64{
+		1, 2000, 5,
+		2, 1000, 5,
+		4, 4000, 5,
+		3, 1000, 5,
+	})
+	cases := []struct {
+		name    string
+		matrix  string
+		weights []float64
+		total   float64
+	}{
+		// the variances add up to the trace of the covariance matrix, the variances of the features
+		{"covariance", "covariance", nil, stat.Variance([]float64{1, 2, 4, 3}, nil) + stat.Variance([]float64{2000, 1000, 4000, 1000}, nil)},
+		// the correlation matrix has a unit diagonal except for the constant feature
+		{"correlation", "correlation", nil, 2},
+		{"weighted correlation", "correlation", []float64{3, 1, 2, 1}, 2},
+	}
+	for _, c := range cases {
+		graph := NewGraph(mat.DenseCopyOf(star))
+		graph.Embedding, graph.PCAMatrix, graph.PCAWeights = embedding, c.matrix, c.weights
+		variances, err := graph.Variances()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total := floats.Sum(variances); math.Abs(total-c.total) > 1e-6 {
+			t.Errorf("%s: the variances add up to %g, expected %g", c.name, total, c.total)
+		}
+	}
+
+	// the covariance is dominated by the large feature, the correlation isn't
+	share := func(matrix string) float64 {
+		graph := NewGraph(mat.DenseCopyOf(star))
+		graph.Embedding, graph.PCAMatrix = embedding, matrix
+		variances, err := graph.Variances()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return variances[0] / floats.Sum(variances)
+	}
+	if covariance, correlation := share("covariance"), share("correlation"); !(covariance > .999) || !(correlation < .95) {
+		t.Errorf("the first component has %g of the covariance and %g of the correlation", covariance, correlation)
+	}
+
+	graph := NewGraph(mat.DenseCopyOf(star))
+	graph.PCAMatrix = "spearman"
+	if _, err := graph.Variances(); err == nil {
+		t.Errorf("unknown pca matrix: no error")
+	}
+}
+
+func TestPCAWeights(t *testing.T) {
+	// a weight counts a node that many times, so a weight of 2 is the same as a repeated row and a
+	// weight of 0 drops the node
+	features := []float64{
+		1, 2,
+		-1, 0,
+		3, 5,
+		0, -2,
+	}
+	cases := []struct {
+		name     string
+		weights  []float64
+		repeated []float64
+	}{
+		{"repeated", []float64{2, 1, 1, 1}, append([]float64{1, 2}, features...)},
+		{"dropped", []float64{1, 0, 1, 1}, []float64{1, 2, 3, 5, 0, -2}},
+	}
+	for _, c := range cases {
+		weighted := NewGraph(mat.DenseCopyOf(star))
+		weighted.Embedding, weighted.PCAWeights = mat.NewDense(4, 2, features), c.weights
+		actual, err := weighted.Variances()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := len(c.repeated) / 2
+		repeated := NewGraph(mat.NewDense(rows, rows, nil))
+		repeated.Embedding = mat.NewDense(rows, 2, c.repeated)
+		expected, err := repeated.Variances()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range expected {
+			if math.Abs(actual[i]-expected[i]) > 1e-9 {
+				t.Errorf("%s: weighted variances %v, expected %v", c.name, actual, expected)
+				break
+			}
+		}
+		if projection, err := weighted.Project(2); err != nil {
+			t.Fatal(err)
+		} else if rows, _ := projection.Dims(); rows != 4 {
+			t.Errorf("%s: the weighted projection has %d nodes, expected 4", c.name, rows)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DecomposeOptions are the options of Decompose, Side is one of EigenSides and defaults to right
+type DecomposeOptions struct {
+	Side string
+}
+
+// Spectrum is an eigendecomposition with the eigenpairs sorted by descending eigenvalue
+// magnitude. Vectors are the left eigenvectors when the side is "left" and the right
+// eigenvectors otherwise, Left is only set when the side is "left" or "both", and Dominant
+// is the index of the largest magnitude eigenvalue.
+type Spectrum struct {
+	Values   []complex128
+	Vectors  *mat.CDense
+	Left     *mat.CDense
+	Dominant int
+}
+
+// Decompose computes the eigendecomposition of a with each eigenvector canonicalized
+func Decompose(a *mat.Dense, options DecomposeOptions) (*Spectrum, error) {
+	size, _ := a.Dims()
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if value := a.At(i, j); math.IsNaN(value) || math.IsInf(value, 0) {
+				return nil, fmt.Errorf("%w: non-finite entry at (%d, %d)", ErrEigen, i, j)
+			}
+		}
+	}
+	kind := mat.EigenRight
+	switch options.Side {
+	case "", "right":
+	case "left":
+		kind = mat.EigenLeft
+	case "both":
+		kind = mat.EigenBoth
+	default:
+		return nil, fmt.Errorf("unknown eigen side %s, expected one of %s", options.Side, strings.Join(EigenSides, ", "))
+	}
+	var eig mat.Eigen
+	ok := eig.Factorize(a, kind)
+	if !ok {
+		return nil, ErrEigen
+	}
+	spectrum := &Spectrum{}
+	values := eig.Values(nil)
+	if kind&mat.EigenLeft != 0 {
+		left := mat.CDense{}
+		eig.LeftVectorsTo(&left)
+		spectrum.Values = SortEigen(values, &left)
+		Canonicalize(&left)
+		spectrum.Left, spectrum.Vectors = &left, &left
+	}
+	if kind&mat.EigenRight != 0 {
+		vectors := mat.CDense{}
+		eig.VectorsTo(&vectors)
+		spectrum.Values = SortEigen(values, &vectors)
+		Canonicalize(&vectors)
+		spectrum.Vectors = &vectors
+	}
+	spectrum.Dominant = Dominant(spectrum.Values)
+	return spectrum, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDecomposeErrors(t *testing.T) {
+	withEntry := func(v float64) *mat.Dense {
+		a := mat.DenseCopyOf(triangle)
+		a.Set(1, 2, v)
+		return a
+	}
+	cases := []struct {
+		name    string
+		a       *mat.Dense
+		options DecomposeOptions
+		err     error
+	}{
+		{"nan", withEntry(math.NaN()), DecomposeOptions{}, ErrEigen},
+		{"inf", withEntry(math.Inf(1)), DecomposeOptions{}, ErrEigen},
+		{"negative inf", withEntry(math.Inf(-1)), DecomposeOptions{Side: "left"}, ErrEigen},
+		{"side", triangle, DecomposeOptions{Side: "up"}, nil},
+	}
+	for _, c := range cases {
+		spectrum, err := Decompose(c.a, c.options)
+		if err == nil || spectrum != nil {
+			t.Errorf("%s: no error", c.name)
+			continue
+		}
+		if c.err != nil && !errors.Is(err, c.err) {
+			t.Errorf("%s: error %v, expected %v", c.name, err, c.err)
+		}
+	}
+
+	// the graph returns the error of the decomposition instead of panicking
+	graph := NewGraph(withEntry(math.NaN()))
+	if _, err := graph.Rank(); !errors.Is(err, ErrEigen) {
+		t.Errorf("rank: error %v, expected %v", err, ErrEigen)
+	}
+	if _, err := graph.Project(1); !errors.Is(err, ErrEigen) {
+		t.Errorf("project: error %v, expected %v", err, ErrEigen)
+	}
+	graph = NewGraph(mat.DenseCopyOf(triangle))
+	graph.PCAWeights = []float64{1, 1}
+	if _, err := graph.Project(1); !errors.Is(err, ErrPCA) {
+		t.Errorf("project with 2 weights for 3 nodes: error %v, expected %v", err, ErrPCA)
+	}
+	graph.PCAWeights, graph.PCAMode = nil, "phase"
+	if _, err := graph.Project(1); err == nil {
+		t.Errorf("project with an unknown pca mode: no error")
+	}
+}
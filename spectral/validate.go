@@ -0,0 +1,99 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	// ErrNegative is returned when the adjacency matrix has negative entries
+	ErrNegative = errors.New("adjacency matrix has negative entries")
+	// ErrAsymmetric is returned when the adjacency matrix of an undirected graph is not symmetric
+	ErrAsymmetric = errors.New("adjacency matrix is not symmetric")
+	// ErrSelfLoops is returned when the adjacency matrix has self-loops that aren't allowed
+	ErrSelfLoops = errors.New("adjacency matrix has self-loops")
+	// ErrDegenerate is returned when the adjacency matrix is a multiple of the identity, every
+	// vector is an eigenvector of it so there is no dominant eigenvector to rank the nodes by
+	ErrDegenerate = errors.New("adjacency matrix is degenerate")
+)
+
+// Validate checks that the adjacency matrix is non-negative, symmetric if symmetric is set and
+// has a zero diagonal unless selfLoops is set, returning an error for each check that fails
+func Validate(a *mat.Dense, symmetric, selfLoops bool) []error {
+	var problems []error
+	size, _ := a.Dims()
+
+	negative, first := 0, [2]int{}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if a.At(i, j) < 0 {
+				if negative == 0 {
+					first = [2]int{i, j}
+				}
+				negative++
+			}
+		}
+	}
+	if negative > 0 {
+		problems = append(problems, fmt.Errorf("%w: %d negative entries, the first is %f at (%d, %d)",
+			ErrNegative, negative, a.At(first[0], first[1]), first[0], first[1]))
+	}
+
+	if symmetric {
+	asymmetric:
+		for i := 0; i < size; i++ {
+			for j := i + 1; j < size; j++ {
+				if math.Abs(a.At(i, j)-a.At(j, i)) > Tolerance {
+					problems = append(problems, fmt.Errorf("%w: (%d, %d) is %f, (%d, %d) is %f",
+						ErrAsymmetric, i, j, a.At(i, j), j, i, a.At(j, i)))
+					break asymmetric
+				}
+			}
+		}
+	}
+
+	if !selfLoops {
+		loops := 0
+		for i := 0; i < size; i++ {
+			if a.At(i, i) != 0 {
+				loops++
+			}
+		}
+		if loops > 0 {
+			problems = append(problems, fmt.Errorf("%w: %d nonzero diagonal entries", ErrSelfLoops, loops))
+		}
+	}
+	return problems
+}
+
+// Degenerate returns ErrDegenerate with the reason when the adjacency matrix is a multiple of
+// the identity within Tolerance, which includes the zero matrix and a single node
+func Degenerate(a *mat.Dense) error {
+	size, _ := a.Dims()
+	if size == 1 {
+		return fmt.Errorf("%w: a single node", ErrDegenerate)
+	}
+	c := a.At(0, 0)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			expected := 0.0
+			if i == j {
+				expected = c
+			}
+			if math.Abs(a.At(i, j)-expected) > Tolerance {
+				return nil
+			}
+		}
+	}
+	if math.Abs(c) <= Tolerance {
+		return fmt.Errorf("%w: no edges", ErrDegenerate)
+	}
+	return fmt.Errorf("%w: %g times the identity", ErrDegenerate, c)
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spectral
+
+import (
+	"errors"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDegenerate(t *testing.T) {
+	identity := func(size int, c float64) *mat.Dense {
+		a := mat.NewDense(size, size, nil)
+		for i := 0; i < size; i++ {
+			a.Set(i, i, c)
+		}
+		return a
+	}
+	nearly := identity(3, 2)
+	nearly.Set(0, 2, Tolerance/2)
+	uneven := identity(3, 2)
+	uneven.Set(1, 1, 3)
+	cases := []struct {
+		name       string
+		a          *mat.Dense
+		degenerate string
+	}{
+		{"single node", mat.NewDense(1, 1, []float64{4}), "adjacency matrix is degenerate: a single node"},
+		{"no edges", mat.NewDense(3, 3, nil), "adjacency matrix is degenerate: no edges"},
+		{"identity", identity(3, 1), "adjacency matrix is degenerate: 1 times the identity"},
+		{"scaled identity", identity(2, -2.5), "adjacency matrix is degenerate: -2.5 times the identity"},
+		{"within tolerance", nearly, "adjacency matrix is degenerate: 2 times the identity"},
+		{"uneven diagonal", uneven, ""},
+		{"triangle", triangle, ""},
+		{"self-loop", dense([]float64{1, 0}, []float64{0, 0}), ""},
+	}
+	for _, c := range cases {
+		err := Degenerate(c.a)
+		if c.degenerate == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrDegenerate) || err.Error() != c.degenerate {
+			t.Errorf("%s: error %v, expected %s", c.name, err, c.degenerate)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/pointlander/truther/spectral"
+)
+
+// StreamOptions are the options for ranking a stream of edges
+type StreamOptions struct {
+	Directed bool
+	// Interval is the time between rankings, 0 disables the timer
+	Interval time.Duration
+	// Every is the number of edges between rankings, 0 disables the count
+	Every int
+	// TopK is the number of nodes printed, 0 prints every node
+	TopK       int
+	Iterations int
+	Tol        float64
+	// MaxSize is the largest number of nodes, edges with larger node ids are skipped, 0 disables the limit
+	MaxSize int
+}
+
+// Stream reads edge list lines from input until it ends and prints the top nodes of the power
+// iteration ranking to output every Interval and every Every edges, and once more at the end if
+// edges arrived since the last ranking. An edge sets the weight of its entry of the adjacency
+// matrix, so a weight of 0 removes it. Each ranking starts power iteration from the previous
+// dominant eigenvector, like Graph.Rank this converges slowly or not at all when the two largest
+// eigenvalue magnitudes are close, as they are for bipartite graphs. Malformed lines are skipped
+// with a warning. Stream returns nil when ctx is done, an interrupt is how a stream that never
+// ends is stopped.
+func Stream(ctx context.Context, input io.Reader, output io.Writer, options StreamOptions) error {
+	lines, failed := make(chan string), make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(input)
+		// failed is buffered, so the error is sent before lines is closed on every exit path
+		defer close(lines)
+		defer func() {
+			failed <- scanner.Err()
+		}()
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var tick <-chan time.Time
+	if options.Interval > 0 {
+		ticker := time.NewTicker(options.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// storage doubles in size as nodes arrive and adjacency is the view of the nodes seen so far
+	var storage, adjacency *mat.Dense
+	var warm []float64
+	writer := bufio.NewWriter(output)
+	nodes, edges, pending, line := 0, 0, 0, 0
+	grow := func(size int) {
+		capacity := 0
+		if storage != nil {
+			capacity, _ = storage.Dims()
+		}
+		if size > capacity {
+			if capacity *= 2; capacity < size {
+				capacity = size
+			}
+			grown := mat.NewDense(capacity, capacity, nil)
+			if adjacency != nil {
+				n, _ := adjacency.Dims()
+				grown.Slice(0, n, 0, n).(*mat.Dense).Copy(adjacency)
+			}
+			storage = grown
+		}
+		adjacency = storage.Slice(0, size, 0, size).(*mat.Dense)
+		for len(warm) < size {
+			warm = append(warm, 1/math.Sqrt(float64(size)))
+		}
+		nodes = size
+	}
+	// rank returns false if ctx is done before power iteration converges
+	rank := func() (bool, error) {
+		if pending == 0 {
+			return true, nil
+		}
+		value, vector, _, err := spectral.PowerIterationFrom(ctx, adjacency, warm, options.Iterations, options.Tol)
+		if errors.Is(err, spectral.ErrCanceled) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		pending, warm = 0, vector
+		scores := make([]float64, len(vector))
+		for i, v := range vector {
+			scores[i] = math.Abs(v)
+		}
+		fmt.Fprintf(writer, "edges %d nodes %d eigenvalue %g\n", edges, len(vector), value)
+		for i, node := range TopK(spectral.RankScores(scores), options.TopK) {
+			fmt.Fprintln(writer, i, node, scores[node])
+		}
+		fmt.Fprintln(writer)
+		return true, writer.Flush()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return writer.Flush()
+		case <-tick:
+			if ok, err := rank(); !ok || err != nil {
+				return err
+			}
+		case text, ok := <-lines:
+			if !ok {
+				if ctx.Err() != nil {
+					return writer.Flush()
+				}
+				if err := <-failed; err != nil {
+					return fmt.Errorf("stdin: %v", err)
+				}
+				if edges == 0 {
+					return errors.New("stdin: no edges")
+				}
+				_, err := rank()
+				return err
+			}
+			line++
+			edge, ok, err := ParseEdge(text)
+			if err != nil {
+				Log.Warnf("stdin: line %d: %v, skipped", line, err)
+				continue
+			} else if !ok {
+				continue
+			}
+			size := edge.Source + 1
+			if edge.Destination+1 > size {
+				size = edge.Destination + 1
+			}
+			if err := CheckSize(size, options.MaxSize); err != nil {
+				Log.Warnf("stdin: line %d: %v, skipped", line, err)
+				continue
+			}
+			if size > nodes {
+				grow(size)
+			}
+			adjacency.Set(edge.Source, edge.Destination, edge.Weight)
+			if !options.Directed {
+				adjacency.Set(edge.Destination, edge.Source, edge.Weight)
+			}
+			edges++
+			pending++
+			if options.Every > 0 && edges%options.Every == 0 {
+				if ok, err := rank(); !ok || err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 The Truther Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Timer records how long each phase of a run takes, the methods of a nil timer do nothing
+// so that the phases can be marked whether or not timing is enabled
+type Timer struct {
+	Names     []string
+	Durations []time.Duration
+	last      time.Time
+}
+
+// NewTimer creates a timer, the first phase starts now
+func NewTimer() *Timer {
+	return &Timer{last: time.Now()}
+}
+
+// Mark ends the phase that started at the previous mark
+func (t *Timer) Mark(name string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	t.Names = append(t.Names, name)
+	t.Durations = append(t.Durations, now.Sub(t.last))
+	t.last = now
+}
+
+// Print writes the duration of each phase and the total
+func (t *Timer) Print(output io.Writer) {
+	if t == nil {
+		return
+	}
+	total := time.Duration(0)
+	fmt.Fprintln(output, "timing")
+	for i, name := range t.Names {
+		fmt.Fprintln(output, name, t.Durations[i])
+		total += t.Durations[i]
+	}
+	fmt.Fprintln(output, "total", total)
+}